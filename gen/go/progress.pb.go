@@ -29,6 +29,7 @@ const (
 	ProgressKind_PROGRESS ProgressKind = 2
 	ProgressKind_SUCCESS  ProgressKind = 3
 	ProgressKind_FAILURE  ProgressKind = 4
+	ProgressKind_WARNING  ProgressKind = 5
 )
 
 // Enum value maps for ProgressKind.
@@ -39,6 +40,7 @@ var (
 		2: "PROGRESS",
 		3: "SUCCESS",
 		4: "FAILURE",
+		5: "WARNING",
 	}
 	ProgressKind_value = map[string]int32{
 		"UNKNOWN":  0,
@@ -46,6 +48,7 @@ var (
 		"PROGRESS": 2,
 		"SUCCESS":  3,
 		"FAILURE":  4,
+		"WARNING":  5,
 	}
 )
 
@@ -135,13 +138,14 @@ const file_progress_proto_rawDesc = "" +
 	"\x0eprogress.proto\x12\x06gitops\"L\n" +
 	"\bProgress\x12(\n" +
 	"\x04kind\x18\x01 \x01(\x0e2\x14.gitops.ProgressKindR\x04kind\x12\x16\n" +
-	"\x06status\x18\x02 \x01(\tR\x06status*P\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status*]\n" +
 	"\fProgressKind\x12\v\n" +
 	"\aUNKNOWN\x10\x00\x12\v\n" +
 	"\aHEADING\x10\x01\x12\f\n" +
 	"\bPROGRESS\x10\x02\x12\v\n" +
 	"\aSUCCESS\x10\x03\x12\v\n" +
-	"\aFAILURE\x10\x04B.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
+	"\aFAILURE\x10\x04\x12\v\n" +
+	"\aWARNING\x10\x05B.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
 
 var (
 	file_progress_proto_rawDescOnce sync.Once
@@ -0,0 +1,329 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: prune.proto
+
+package gitops
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PruneReviewsRequest struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	ConfigRepository         *Repository            `protobuf:"bytes,1,opt,name=config_repository,json=configRepository,proto3" json:"config_repository,omitempty"`
+	Environment              string                 `protobuf:"bytes,2,opt,name=environment,proto3" json:"environment,omitempty"`
+	AppName                  string                 `protobuf:"bytes,3,opt,name=app_name,json=appName,proto3" json:"app_name,omitempty"`
+	DeletedUpdateIdentifiers []string               `protobuf:"bytes,4,rep,name=deleted_update_identifiers,json=deletedUpdateIdentifiers,proto3" json:"deleted_update_identifiers,omitempty"`
+	DryRun                   bool                   `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *PruneReviewsRequest) Reset() {
+	*x = PruneReviewsRequest{}
+	mi := &file_prune_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneReviewsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneReviewsRequest) ProtoMessage() {}
+
+func (x *PruneReviewsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_prune_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneReviewsRequest.ProtoReflect.Descriptor instead.
+func (*PruneReviewsRequest) Descriptor() ([]byte, []int) {
+	return file_prune_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PruneReviewsRequest) GetConfigRepository() *Repository {
+	if x != nil {
+		return x.ConfigRepository
+	}
+	return nil
+}
+
+func (x *PruneReviewsRequest) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *PruneReviewsRequest) GetAppName() string {
+	if x != nil {
+		return x.AppName
+	}
+	return ""
+}
+
+func (x *PruneReviewsRequest) GetDeletedUpdateIdentifiers() []string {
+	if x != nil {
+		return x.DeletedUpdateIdentifiers
+	}
+	return nil
+}
+
+func (x *PruneReviewsRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type PruneReviewsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pruned        []*PrunedBranch        `protobuf:"bytes,1,rep,name=pruned,proto3" json:"pruned,omitempty"`
+	DryRun        bool                   `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PruneReviewsResponse) Reset() {
+	*x = PruneReviewsResponse{}
+	mi := &file_prune_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PruneReviewsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PruneReviewsResponse) ProtoMessage() {}
+
+func (x *PruneReviewsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_prune_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PruneReviewsResponse.ProtoReflect.Descriptor instead.
+func (*PruneReviewsResponse) Descriptor() ([]byte, []int) {
+	return file_prune_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PruneReviewsResponse) GetPruned() []*PrunedBranch {
+	if x != nil {
+		return x.Pruned
+	}
+	return nil
+}
+
+func (x *PruneReviewsResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+type PrunedBranch struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Branch           string                 `protobuf:"bytes,1,opt,name=branch,proto3" json:"branch,omitempty"`
+	Environment      string                 `protobuf:"bytes,2,opt,name=environment,proto3" json:"environment,omitempty"`
+	AppName          string                 `protobuf:"bytes,3,opt,name=app_name,json=appName,proto3" json:"app_name,omitempty"`
+	UpdateIdentifier string                 `protobuf:"bytes,4,opt,name=update_identifier,json=updateIdentifier,proto3" json:"update_identifier,omitempty"`
+	Reason           string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	ReviewUrl        string                 `protobuf:"bytes,6,opt,name=review_url,json=reviewUrl,proto3" json:"review_url,omitempty"`
+	BranchDeleted    bool                   `protobuf:"varint,7,opt,name=branch_deleted,json=branchDeleted,proto3" json:"branch_deleted,omitempty"`
+	Error            string                 `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PrunedBranch) Reset() {
+	*x = PrunedBranch{}
+	mi := &file_prune_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrunedBranch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrunedBranch) ProtoMessage() {}
+
+func (x *PrunedBranch) ProtoReflect() protoreflect.Message {
+	mi := &file_prune_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrunedBranch.ProtoReflect.Descriptor instead.
+func (*PrunedBranch) Descriptor() ([]byte, []int) {
+	return file_prune_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PrunedBranch) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetEnvironment() string {
+	if x != nil {
+		return x.Environment
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetAppName() string {
+	if x != nil {
+		return x.AppName
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetUpdateIdentifier() string {
+	if x != nil {
+		return x.UpdateIdentifier
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetReviewUrl() string {
+	if x != nil {
+		return x.ReviewUrl
+	}
+	return ""
+}
+
+func (x *PrunedBranch) GetBranchDeleted() bool {
+	if x != nil {
+		return x.BranchDeleted
+	}
+	return false
+}
+
+func (x *PrunedBranch) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_prune_proto protoreflect.FileDescriptor
+
+const file_prune_proto_rawDesc = "" +
+	"\n" +
+	"\vprune.proto\x12\x06gitops\x1a\fcommon.proto\"\xea\x01\n" +
+	"\x13PruneReviewsRequest\x12?\n" +
+	"\x11config_repository\x18\x01 \x01(\v2\x12.gitops.RepositoryR\x10configRepository\x12 \n" +
+	"\venvironment\x18\x02 \x01(\tR\venvironment\x12\x19\n" +
+	"\bapp_name\x18\x03 \x01(\tR\aappName\x12<\n" +
+	"\x1adeleted_update_identifiers\x18\x04 \x03(\tR\x18deletedUpdateIdentifiers\x12\x17\n" +
+	"\adry_run\x18\x05 \x01(\bR\x06dryRun\"]\n" +
+	"\x14PruneReviewsResponse\x12,\n" +
+	"\x06pruned\x18\x01 \x03(\v2\x14.gitops.PrunedBranchR\x06pruned\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\"\x84\x02\n" +
+	"\fPrunedBranch\x12\x16\n" +
+	"\x06branch\x18\x01 \x01(\tR\x06branch\x12 \n" +
+	"\venvironment\x18\x02 \x01(\tR\venvironment\x12\x19\n" +
+	"\bapp_name\x18\x03 \x01(\tR\aappName\x12+\n" +
+	"\x11update_identifier\x18\x04 \x01(\tR\x10updateIdentifier\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12\x1d\n" +
+	"\n" +
+	"review_url\x18\x06 \x01(\tR\treviewUrl\x12%\n" +
+	"\x0ebranch_deleted\x18\a \x01(\bR\rbranchDeleted\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05errorB.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
+
+var (
+	file_prune_proto_rawDescOnce sync.Once
+	file_prune_proto_rawDescData []byte
+)
+
+func file_prune_proto_rawDescGZIP() []byte {
+	file_prune_proto_rawDescOnce.Do(func() {
+		file_prune_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_prune_proto_rawDesc), len(file_prune_proto_rawDesc)))
+	})
+	return file_prune_proto_rawDescData
+}
+
+var file_prune_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_prune_proto_goTypes = []any{
+	(*PruneReviewsRequest)(nil),  // 0: gitops.PruneReviewsRequest
+	(*PruneReviewsResponse)(nil), // 1: gitops.PruneReviewsResponse
+	(*PrunedBranch)(nil),         // 2: gitops.PrunedBranch
+	(*Repository)(nil),           // 3: gitops.Repository
+}
+var file_prune_proto_depIdxs = []int32{
+	3, // 0: gitops.PruneReviewsRequest.config_repository:type_name -> gitops.Repository
+	2, // 1: gitops.PruneReviewsResponse.pruned:type_name -> gitops.PrunedBranch
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_prune_proto_init() }
+func file_prune_proto_init() {
+	if File_prune_proto != nil {
+		return
+	}
+	file_common_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_prune_proto_rawDesc), len(file_prune_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_prune_proto_goTypes,
+		DependencyIndexes: file_prune_proto_depIdxs,
+		MessageInfos:      file_prune_proto_msgTypes,
+	}.Build()
+	File_prune_proto = out.File
+	file_prune_proto_goTypes = nil
+	file_prune_proto_depIdxs = nil
+}
@@ -28,6 +28,7 @@ type Summary struct {
 	DryRun            bool                   `protobuf:"varint,3,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
 	Review            *ReviewSummary         `protobuf:"bytes,4,opt,name=review,proto3" json:"review,omitempty"`
 	Environment       *EnvironmentSummary    `protobuf:"bytes,5,opt,name=environment,proto3" json:"environment,omitempty"`
+	Findings          []*ValidationFinding   `protobuf:"bytes,6,rep,name=findings,proto3" json:"findings,omitempty"`
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -97,6 +98,13 @@ func (x *Summary) GetEnvironment() *EnvironmentSummary {
 	return nil
 }
 
+func (x *Summary) GetFindings() []*ValidationFinding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
 type ReviewSummary struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Created       bool                   `protobuf:"varint,1,opt,name=created,proto3" json:"created,omitempty"`
@@ -217,17 +225,86 @@ func (x *EnvironmentSummary) GetRefName() string {
 	return ""
 }
 
+type ValidationFinding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Severity      string                 `protobuf:"bytes,1,opt,name=severity,proto3" json:"severity,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	Validator     string                 `protobuf:"bytes,4,opt,name=validator,proto3" json:"validator,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidationFinding) Reset() {
+	*x = ValidationFinding{}
+	mi := &file_response_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidationFinding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidationFinding) ProtoMessage() {}
+
+func (x *ValidationFinding) ProtoReflect() protoreflect.Message {
+	mi := &file_response_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidationFinding.ProtoReflect.Descriptor instead.
+func (*ValidationFinding) Descriptor() ([]byte, []int) {
+	return file_response_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ValidationFinding) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *ValidationFinding) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ValidationFinding) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *ValidationFinding) GetValidator() string {
+	if x != nil {
+		return x.Validator
+	}
+	return ""
+}
+
 var File_response_proto protoreflect.FileDescriptor
 
 const file_response_proto_rawDesc = "" +
 	"\n" +
-	"\x0eresponse.proto\x12\x06gitops\x1a\fcommon.proto\"\xd9\x01\n" +
+	"\x0eresponse.proto\x12\x06gitops\x1a\fcommon.proto\"\x90\x02\n" +
 	"\aSummary\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12.\n" +
 	"\x13updated_files_count\x18\x02 \x01(\x05R\x11updatedFilesCount\x12\x17\n" +
 	"\adry_run\x18\x03 \x01(\bR\x06dryRun\x12-\n" +
 	"\x06review\x18\x04 \x01(\v2\x15.gitops.ReviewSummaryR\x06review\x12<\n" +
-	"\venvironment\x18\x05 \x01(\v2\x1a.gitops.EnvironmentSummaryR\venvironment\"Y\n" +
+	"\venvironment\x18\x05 \x01(\v2\x1a.gitops.EnvironmentSummaryR\venvironment\x125\n" +
+	"\bfindings\x18\x06 \x03(\v2\x19.gitops.ValidationFindingR\bfindings\"Y\n" +
 	"\rReviewSummary\x12\x18\n" +
 	"\acreated\x18\x01 \x01(\bR\acreated\x12\x10\n" +
 	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1c\n" +
@@ -237,7 +314,12 @@ const file_response_proto_rawDesc = "" +
 	"repository\x18\x01 \x01(\v2\x12.gitops.RepositoryR\n" +
 	"repository\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
-	"\bref_name\x18\x03 \x01(\tR\arefNameB.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
+	"\bref_name\x18\x03 \x01(\tR\arefName\"{\n" +
+	"\x11ValidationFinding\x12\x1a\n" +
+	"\bseverity\x18\x01 \x01(\tR\bseverity\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x1c\n" +
+	"\tvalidator\x18\x04 \x01(\tR\tvalidatorB.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
 
 var (
 	file_response_proto_rawDescOnce sync.Once
@@ -251,22 +333,24 @@ func file_response_proto_rawDescGZIP() []byte {
 	return file_response_proto_rawDescData
 }
 
-var file_response_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_response_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_response_proto_goTypes = []any{
 	(*Summary)(nil),            // 0: gitops.Summary
 	(*ReviewSummary)(nil),      // 1: gitops.ReviewSummary
 	(*EnvironmentSummary)(nil), // 2: gitops.EnvironmentSummary
-	(*Repository)(nil),         // 3: gitops.Repository
+	(*ValidationFinding)(nil),  // 3: gitops.ValidationFinding
+	(*Repository)(nil),         // 4: gitops.Repository
 }
 var file_response_proto_depIdxs = []int32{
 	1, // 0: gitops.Summary.review:type_name -> gitops.ReviewSummary
 	2, // 1: gitops.Summary.environment:type_name -> gitops.EnvironmentSummary
-	3, // 2: gitops.EnvironmentSummary.repository:type_name -> gitops.Repository
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	3, // 2: gitops.Summary.findings:type_name -> gitops.ValidationFinding
+	4, // 3: gitops.EnvironmentSummary.repository:type_name -> gitops.Repository
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_response_proto_init() }
@@ -281,7 +365,7 @@ func file_response_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_response_proto_rawDesc), len(file_response_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -205,7 +205,7 @@ var File_service_proto protoreflect.FileDescriptor
 
 const file_service_proto_rawDesc = "" +
 	"\n" +
-	"\rservice.proto\x12\x06gitops\x1a\rrequest.proto\x1a\x0eresponse.proto\x1a\x0eprogress.proto\x1a\ferrors.proto\"\x83\x01\n" +
+	"\rservice.proto\x12\x06gitops\x1a\rrequest.proto\x1a\x0eresponse.proto\x1a\x0eprogress.proto\x1a\ferrors.proto\x1a\vprune.proto\"\x83\x01\n" +
 	"\x0fManifestRequest\x12<\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x1e.gitops.UpdateManifestMetadataH\x00R\bmetadata\x12'\n" +
 	"\x04file\x18\x02 \x01(\v2\x11.gitops.FileChunkH\x00R\x04fileB\t\n" +
@@ -215,9 +215,10 @@ const file_service_proto_rawDesc = "" +
 	"\asummary\x18\x02 \x01(\v2\x0f.gitops.SummaryH\x00R\asummary\x12%\n" +
 	"\x05error\x18\x03 \x01(\v2\r.gitops.ErrorH\x00R\x05errorB\n" +
 	"\n" +
-	"\bresponse2R\n" +
+	"\bresponse2\x9d\x01\n" +
 	"\x06GitOps\x12H\n" +
-	"\x0fUpdateManifests\x12\x17.gitops.ManifestRequest\x1a\x18.gitops.ManifestResponse(\x010\x01B.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
+	"\x0fUpdateManifests\x12\x17.gitops.ManifestRequest\x1a\x18.gitops.ManifestResponse(\x010\x01\x12I\n" +
+	"\fPruneReviews\x12\x1b.gitops.PruneReviewsRequest\x1a\x1c.gitops.PruneReviewsResponseB.Z,github.com/tvandinther/gitops-manager/gitopsb\x06proto3"
 
 var (
 	file_service_proto_rawDescOnce sync.Once
@@ -240,6 +241,8 @@ var file_service_proto_goTypes = []any{
 	(*Progress)(nil),               // 4: gitops.Progress
 	(*Summary)(nil),                // 5: gitops.Summary
 	(*Error)(nil),                  // 6: gitops.Error
+	(*PruneReviewsRequest)(nil),    // 7: gitops.PruneReviewsRequest
+	(*PruneReviewsResponse)(nil),   // 8: gitops.PruneReviewsResponse
 }
 var file_service_proto_depIdxs = []int32{
 	2, // 0: gitops.ManifestRequest.metadata:type_name -> gitops.UpdateManifestMetadata
@@ -248,9 +251,11 @@ var file_service_proto_depIdxs = []int32{
 	5, // 3: gitops.ManifestResponse.summary:type_name -> gitops.Summary
 	6, // 4: gitops.ManifestResponse.error:type_name -> gitops.Error
 	0, // 5: gitops.GitOps.UpdateManifests:input_type -> gitops.ManifestRequest
-	1, // 6: gitops.GitOps.UpdateManifests:output_type -> gitops.ManifestResponse
-	6, // [6:7] is the sub-list for method output_type
-	5, // [5:6] is the sub-list for method input_type
+	7, // 6: gitops.GitOps.PruneReviews:input_type -> gitops.PruneReviewsRequest
+	1, // 7: gitops.GitOps.UpdateManifests:output_type -> gitops.ManifestResponse
+	8, // 8: gitops.GitOps.PruneReviews:output_type -> gitops.PruneReviewsResponse
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
 	5, // [5:5] is the sub-list for extension type_name
 	5, // [5:5] is the sub-list for extension extendee
 	0, // [0:5] is the sub-list for field type_name
@@ -265,6 +270,7 @@ func file_service_proto_init() {
 	file_response_proto_init()
 	file_progress_proto_init()
 	file_errors_proto_init()
+	file_prune_proto_init()
 	file_service_proto_msgTypes[0].OneofWrappers = []any{
 		(*ManifestRequest_Metadata)(nil),
 		(*ManifestRequest_File)(nil),
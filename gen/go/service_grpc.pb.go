@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	GitOps_UpdateManifests_FullMethodName = "/gitops.GitOps/UpdateManifests"
+	GitOps_PruneReviews_FullMethodName    = "/gitops.GitOps/PruneReviews"
 )
 
 // GitOpsClient is the client API for GitOps service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GitOpsClient interface {
 	UpdateManifests(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ManifestRequest, ManifestResponse], error)
+	PruneReviews(ctx context.Context, in *PruneReviewsRequest, opts ...grpc.CallOption) (*PruneReviewsResponse, error)
 }
 
 type gitOpsClient struct {
@@ -50,11 +52,22 @@ func (c *gitOpsClient) UpdateManifests(ctx context.Context, opts ...grpc.CallOpt
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type GitOps_UpdateManifestsClient = grpc.BidiStreamingClient[ManifestRequest, ManifestResponse]
 
+func (c *gitOpsClient) PruneReviews(ctx context.Context, in *PruneReviewsRequest, opts ...grpc.CallOption) (*PruneReviewsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PruneReviewsResponse)
+	err := c.cc.Invoke(ctx, GitOps_PruneReviews_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GitOpsServer is the server API for GitOps service.
 // All implementations must embed UnimplementedGitOpsServer
 // for forward compatibility.
 type GitOpsServer interface {
 	UpdateManifests(grpc.BidiStreamingServer[ManifestRequest, ManifestResponse]) error
+	PruneReviews(context.Context, *PruneReviewsRequest) (*PruneReviewsResponse, error)
 	mustEmbedUnimplementedGitOpsServer()
 }
 
@@ -68,6 +81,9 @@ type UnimplementedGitOpsServer struct{}
 func (UnimplementedGitOpsServer) UpdateManifests(grpc.BidiStreamingServer[ManifestRequest, ManifestResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method UpdateManifests not implemented")
 }
+func (UnimplementedGitOpsServer) PruneReviews(context.Context, *PruneReviewsRequest) (*PruneReviewsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PruneReviews not implemented")
+}
 func (UnimplementedGitOpsServer) mustEmbedUnimplementedGitOpsServer() {}
 func (UnimplementedGitOpsServer) testEmbeddedByValue()                {}
 
@@ -96,13 +112,36 @@ func _GitOps_UpdateManifests_Handler(srv interface{}, stream grpc.ServerStream)
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type GitOps_UpdateManifestsServer = grpc.BidiStreamingServer[ManifestRequest, ManifestResponse]
 
+func _GitOps_PruneReviews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PruneReviewsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GitOpsServer).PruneReviews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GitOps_PruneReviews_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GitOpsServer).PruneReviews(ctx, req.(*PruneReviewsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GitOps_ServiceDesc is the grpc.ServiceDesc for GitOps service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var GitOps_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "gitops.GitOps",
 	HandlerType: (*GitOpsServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PruneReviews",
+			Handler:    _GitOps_PruneReviews_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "UpdateManifests",
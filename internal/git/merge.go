@@ -0,0 +1,282 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	pgit "github.com/tvandinther/gitops-manager/pkg/gitops/git"
+)
+
+var ErrNonFastForward = errors.New("branch cannot be fast-forwarded")
+
+// Moves a branch forward to the commit of another, failing with ErrNonFastForward if the branch has diverged. A missing
+// branch is created.
+func FastForward(repo *git.Repository, into, from plumbing.ReferenceName) (plumbing.Hash, error) {
+	intoRef, fromCommit, err := mergeHeads(repo, into, from)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if intoRef != nil {
+		intoCommit, err := repo.CommitObject(intoRef.Hash())
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to get commit of %s: %w", into.Short(), err)
+		}
+		isAncestor, err := intoCommit.IsAncestor(fromCommit)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to compare %s and %s: %w", into.Short(), from.Short(), err)
+		}
+		if !isAncestor {
+			return plumbing.ZeroHash, fmt.Errorf("%s into %s: %w", from.Short(), into.Short(), ErrNonFastForward)
+		}
+	}
+
+	return fromCommit.Hash, updateBranch(repo, into, intoRef, fromCommit.Hash)
+}
+
+// Merges one branch into another without a worktree by creating a merge commit, or a commit with a single parent if
+// squash is set. Files changed on both branches since their merge base are reported as conflicts rather than merged
+// line by line. A missing branch is created at the commit of the other branch.
+func Merge(repo *git.Repository, into, from plumbing.ReferenceName, author *pgit.Author, message string, squash bool) (plumbing.Hash, error) {
+	intoRef, fromCommit, err := mergeHeads(repo, into, from)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if intoRef == nil {
+		return fromCommit.Hash, updateBranch(repo, into, nil, fromCommit.Hash)
+	}
+
+	intoCommit, err := repo.CommitObject(intoRef.Hash())
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to get commit of %s: %w", into.Short(), err)
+	}
+
+	upToDate, err := fromCommit.IsAncestor(intoCommit)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to compare %s and %s: %w", into.Short(), from.Short(), err)
+	}
+	if upToDate {
+		return intoCommit.Hash, nil
+	}
+
+	treeHash, err := mergeTrees(repo, intoCommit, fromCommit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	parents := []plumbing.Hash{intoCommit.Hash}
+	if !squash {
+		parents = append(parents, fromCommit.Hash)
+	}
+	signature := object.Signature{
+		Name:  author.Name,
+		Email: author.Email,
+		When:  time.Now(),
+	}
+	commitHash, err := storeObject(repo, &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	})
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store merge commit: %w", err)
+	}
+
+	return commitHash, updateBranch(repo, into, intoRef, commitHash)
+}
+
+// Moves a branch to a commit, failing if the branch no longer points at the expected commit
+func ResetBranch(repo *git.Repository, name plumbing.ReferenceName, expected, hash plumbing.Hash) error {
+	return updateBranch(repo, name, plumbing.NewHashReference(name, expected), hash)
+}
+
+// Returns the current reference of the branch being merged into, or nil if it does not exist, and the commit being
+// merged
+func mergeHeads(repo *git.Repository, into, from plumbing.ReferenceName) (*plumbing.Reference, *object.Commit, error) {
+	fromRef, err := repo.Reference(from, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get reference %s: %w", from, err)
+	}
+	fromCommit, err := repo.CommitObject(fromRef.Hash())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get commit of %s: %w", from.Short(), err)
+	}
+
+	intoRef, err := repo.Reference(into, true)
+	if err == plumbing.ErrReferenceNotFound {
+		return nil, fromCommit, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get reference %s: %w", into, err)
+	}
+
+	return intoRef, fromCommit, nil
+}
+
+// Updates a branch to a commit, failing if the branch has moved since it was read
+func updateBranch(repo *git.Repository, name plumbing.ReferenceName, old *plumbing.Reference, hash plumbing.Hash) error {
+	err := repo.Storer.CheckAndSetReference(plumbing.NewHashReference(name, hash), old)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", name.Short(), err)
+	}
+
+	return nil
+}
+
+// Performs a three-way merge of the trees of two commits against their merge base, returning the merged tree
+func mergeTrees(repo *git.Repository, ours, theirs *object.Commit) (plumbing.Hash, error) {
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to find merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return plumbing.ZeroHash, fmt.Errorf("failed to find merge base: the branches have no common history")
+	}
+
+	baseEntries, err := treeEntries(bases[0])
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ourEntries, err := treeEntries(ours)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	theirEntries, err := treeEntries(theirs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	paths := make(map[string]bool)
+	for _, entries := range []map[string]object.TreeEntry{baseEntries, ourEntries, theirEntries} {
+		for name := range entries {
+			paths[name] = true
+		}
+	}
+
+	merged := make(map[string]object.TreeEntry)
+	conflicts := make([]string, 0)
+	for name := range paths {
+		base, inBase := baseEntries[name]
+		our, inOurs := ourEntries[name]
+		their, inTheirs := theirEntries[name]
+
+		var entry object.TreeEntry
+		var exists bool
+		switch {
+		case inOurs == inTheirs && our == their:
+			entry, exists = our, inOurs
+		case inOurs == inBase && our == base:
+			entry, exists = their, inTheirs
+		case inTheirs == inBase && their == base:
+			entry, exists = our, inOurs
+		default:
+			conflicts = append(conflicts, name)
+			continue
+		}
+		if exists {
+			merged[name] = entry
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return plumbing.ZeroHash, fmt.Errorf("merge conflict in %s", strings.Join(conflicts, ", "))
+	}
+
+	return writeTree(repo, "", merged)
+}
+
+// Returns the file entries of the tree of a commit by path
+func treeEntries(commit *object.Commit) (map[string]object.TreeEntry, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", commit.Hash, err)
+	}
+
+	entries := make(map[string]object.TreeEntry)
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to walk tree of %s: %w", commit.Hash, err)
+		}
+		if entry.Mode != filemode.Dir {
+			entries[name] = entry
+		}
+	}
+
+	return entries, nil
+}
+
+// Writes the tree of a directory from the file entries beneath it, returning its hash
+func writeTree(repo *git.Repository, dir string, files map[string]object.TreeEntry) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	subdirectories := make(map[string]map[string]object.TreeEntry)
+	for name, entry := range files {
+		relative := strings.TrimPrefix(name, dir)
+		child, _, nested := strings.Cut(relative, "/")
+		if !nested {
+			entry.Name = child
+			tree.Entries = append(tree.Entries, entry)
+			continue
+		}
+		if subdirectories[child] == nil {
+			subdirectories[child] = make(map[string]object.TreeEntry)
+		}
+		subdirectories[child][name] = entry
+	}
+
+	for child, subdirectoryFiles := range subdirectories {
+		hash, err := writeTree(repo, path.Join(dir, child)+"/", subdirectoryFiles)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: child, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git orders tree entries as if directory names ended with a slash
+	sortName := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortName(tree.Entries[i]) < sortName(tree.Entries[j])
+	})
+
+	hash, err := storeObject(repo, tree)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store merged tree: %w", err)
+	}
+
+	return hash, nil
+}
+
+type encodable interface {
+	Encode(plumbing.EncodedObject) error
+}
+
+func storeObject(repo *git.Repository, obj encodable) (plumbing.Hash, error) {
+	encoded := repo.Storer.NewEncodedObject()
+	err := obj.Encode(encoded)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return repo.Storer.SetEncodedObject(encoded)
+}
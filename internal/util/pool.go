@@ -0,0 +1,47 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// Calls fn for every item using at most concurrency goroutines, returning the results in the order of the items. A
+// concurrency below one processes items sequentially. Once ctx is cancelled no further items are started and the
+// context error is returned alongside the results of the items which did run.
+func ParallelMap[T any, U any](ctx context.Context, concurrency int, in []T, fn func(ctx context.Context, item T) U) ([]U, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	out := make([]U, len(in))
+	items := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for range min(concurrency, len(in)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				out[i] = fn(ctx, in[i])
+			}
+		}()
+	}
+
+	var err error
+dispatch:
+	for i := range in {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		case items <- i:
+		}
+	}
+	close(items)
+	wg.Wait()
+
+	return out, err
+}
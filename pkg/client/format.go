@@ -16,6 +16,7 @@ type JobSummary struct {
 	DryRun            bool                `json:"dry_run"`
 	Review            *ReviewSummary      `json:"review"`
 	Environment       *EnvironmentSummary `json:"environment"`
+	Findings          []string            `json:"findings,omitempty"`
 }
 
 type ReviewSummary struct {
@@ -40,6 +41,8 @@ func PrintProgress(p *pb.Progress) {
 		fmt.Printf("\033[32m✔ %s\033[0m\n", p.Status)
 	case pb.ProgressKind_FAILURE:
 		fmt.Printf("\033[31m✖ %s\033[0m\n", p.Status)
+	case pb.ProgressKind_WARNING:
+		fmt.Printf("\033[33m⚠ %s\033[0m\n", p.Status)
 	default:
 		fmt.Println(p.Status)
 	}
@@ -59,6 +62,9 @@ func (s *JobSummary) FromProto(p *pb.Summary) {
 		RefName:    p.GetEnvironment().GetRefName(),
 		Repository: p.GetEnvironment().GetRepository().GetUrl(),
 	}
+	for _, f := range p.GetFindings() {
+		s.Findings = append(s.Findings, fmt.Sprintf("%s: %s: %s (%s)", f.GetSeverity(), f.GetPath(), f.GetMessage(), f.GetValidator()))
+	}
 }
 
 func PrettyPrintManifestRequest(req *request.Request) {
@@ -102,13 +108,15 @@ func PrettyPrintJSONBlock(title string, jsonBytes []byte) error {
 }
 
 func printIndentedBlockSorted(data map[string]interface{}, indentLevel int) {
-	var flatKeys, nestedKeys []string
+	var flatKeys, nestedKeys, listKeys []string
 	labelWidth := 0
 
 	for k, v := range data {
 		switch v.(type) {
 		case map[string]interface{}:
 			nestedKeys = append(nestedKeys, k)
+		case []interface{}:
+			listKeys = append(listKeys, k)
 		default:
 			flatKeys = append(flatKeys, k)
 			if len(k) > labelWidth {
@@ -118,6 +126,7 @@ func printIndentedBlockSorted(data map[string]interface{}, indentLevel int) {
 	}
 	sort.Strings(flatKeys)
 	sort.Strings(nestedKeys)
+	sort.Strings(listKeys)
 
 	indent := strings.Repeat("  ", indentLevel)
 
@@ -131,4 +140,12 @@ func printIndentedBlockSorted(data map[string]interface{}, indentLevel int) {
 		fmt.Printf("%s%s:\n", indent, key)
 		printIndentedBlockSorted(val, indentLevel+1)
 	}
+
+	for _, key := range listKeys {
+		val := data[key].([]interface{})
+		fmt.Printf("%s%s:\n", indent, key)
+		for _, item := range val {
+			fmt.Printf("%s  - %v\n", indent, item)
+		}
+	}
 }
@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/tvandinther/gitops-manager/gen/go"
+)
+
+// Requests the server to close the reviews of stale source branches and delete the branches. The deleted update
+// identifiers are pruned regardless of age, such as the branches of a source repository which were deleted.
+func (c *Client) PruneReviews(ctx context.Context, req *pb.PruneReviewsRequest) (*pb.PruneReviewsResponse, error) {
+	res, err := c.grpcClient.PruneReviews(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prune reviews: %w", err)
+	}
+
+	return res, nil
+}
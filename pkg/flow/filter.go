@@ -0,0 +1,110 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	"github.com/tvandinther/gitops-manager/pkg/util"
+)
+
+// Restricts a processor to a subset of files. Patterns are matched against the path relative to the manifests directory
+// using util.MatchGlob, so "*.yaml" matches in any directory and "charts/**" matches everything below charts.
+type FileFilter struct {
+	Include []string // Files to process. Defaults to all files.
+	Exclude []string // Files to skip, even if they match Include
+}
+
+// Returns a filter which only processes files matching any of the patterns
+func Include(patterns ...string) FileFilter {
+	return FileFilter{Include: patterns}
+}
+
+// Returns a filter which skips files matching any of the patterns
+func Exclude(patterns ...string) FileFilter {
+	return FileFilter{Exclude: patterns}
+}
+
+// Reports whether a file passes all of the filters
+func matchesFilters(filters []FileFilter, name string) (bool, error) {
+	for _, filter := range filters {
+		if len(filter.Include) > 0 {
+			included, err := matchesAnyGlob(filter.Include, name)
+			if !included || err != nil {
+				return false, err
+			}
+		}
+		excluded, err := matchesAnyGlob(filter.Exclude, name)
+		if excluded || err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchesAnyGlob(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := util.MatchGlob(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Restricts a mutator to files passing all of the filters. Other files are passed on unchanged.
+func FilterMutator(m gitops.Mutator, filters ...FileFilter) gitops.Mutator {
+	if len(filters) == 0 {
+		return m
+	}
+
+	return &filteredMutator{Mutator: m, filters: filters}
+}
+
+// Restricts a validator to files passing all of the filters. Other files are considered valid.
+func FilterValidator(v gitops.Validator, filters ...FileFilter) gitops.Validator {
+	if len(filters) == 0 {
+		return v
+	}
+
+	return &filteredValidator{Validator: v, filters: filters}
+}
+
+// A mutator which leaves files not matching its filters untouched
+type filteredMutator struct {
+	gitops.Mutator
+	filters []FileFilter
+}
+
+func (f *filteredMutator) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	matched, err := matchesFilters(f.filters, fileContext.Path)
+	if !matched || err != nil {
+		return err
+	}
+
+	return f.Mutator.MutateFile(ctx, request, fileContext, inputFile, outputFile, sendMsg)
+}
+
+// A validator which considers files not matching its filters valid
+type filteredValidator struct {
+	gitops.Validator
+	filters []FileFilter
+}
+
+func (f *filteredValidator) ValidateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	matched, err := matchesFilters(f.filters, fileContext.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !matched {
+		return &gitops.ValidationResult{IsValid: true}, nil
+	}
+
+	return f.Validator.ValidateFile(ctx, request, fileContext, file, sendMsg)
+}
@@ -3,8 +3,9 @@ package flow
 import "github.com/tvandinther/gitops-manager/pkg/gitops"
 
 type Flow struct {
-	Strategies *Strategies
-	Processors *Processors
+	Strategies     *Strategies
+	Processors     *Processors
+	ReviewTemplate *gitops.ReviewTemplate // The title and description of reviews created by any reviewer
 }
 
 type Strategies struct {
@@ -18,22 +19,36 @@ type Strategies struct {
 }
 
 type Processors struct {
-	Mutators   []gitops.Mutator
-	Validators []gitops.Validator
+	Mutators         []gitops.Mutator
+	FileSetMutators  []gitops.FileSetMutator
+	Validators       []gitops.Validator
+	SetValidators    []gitops.SetValidator
+	WarningsAsErrors []string // Environments in which validation warnings are promoted to errors
+	Concurrency      int      // The number of files mutated or validated in parallel. Values below one process files sequentially.
 }
 
 func New(strategies *Strategies) *Flow {
 	return &Flow{
-		Strategies: strategies,
+		Strategies:     strategies,
+		ReviewTemplate: &gitops.ReviewTemplate{},
 		Processors: &Processors{
-			Mutators:   make([]gitops.Mutator, 0),
-			Validators: make([]gitops.Validator, 0),
+			Mutators:         make([]gitops.Mutator, 0),
+			FileSetMutators:  make([]gitops.FileSetMutator, 0),
+			Validators:       make([]gitops.Validator, 0),
+			SetValidators:    make([]gitops.SetValidator, 0),
+			WarningsAsErrors: make([]string, 0),
 		},
 	}
 }
 
-func (f *Flow) AddMutator(m gitops.Mutator) {
-	f.Processors.Mutators = append(f.Processors.Mutators, m)
+// Sets the templates of the title and description of reviews
+func (f *Flow) WithReviewTemplate(t *gitops.ReviewTemplate) {
+	f.ReviewTemplate = t
+}
+
+// Adds a mutator, optionally restricted to files passing all of the filters
+func (f *Flow) AddMutator(m gitops.Mutator, filters ...FileFilter) {
+	f.Processors.Mutators = append(f.Processors.Mutators, FilterMutator(m, filters...))
 }
 
 // Overwrites any mutators previously added with AddMutator
@@ -41,11 +56,40 @@ func (f *Flow) WithMutators(ms ...gitops.Mutator) {
 	f.Processors.Mutators = ms
 }
 
-func (f *Flow) AddValidator(v gitops.Validator) {
-	f.Processors.Validators = append(f.Processors.Validators, v)
+func (f *Flow) AddFileSetMutator(m gitops.FileSetMutator) {
+	f.Processors.FileSetMutators = append(f.Processors.FileSetMutators, m)
+}
+
+// Overwrites any file set mutators previously added with AddFileSetMutator
+func (f *Flow) WithFileSetMutators(ms ...gitops.FileSetMutator) {
+	f.Processors.FileSetMutators = ms
+}
+
+// Adds a validator, optionally restricted to files passing all of the filters
+func (f *Flow) AddValidator(v gitops.Validator, filters ...FileFilter) {
+	f.Processors.Validators = append(f.Processors.Validators, FilterValidator(v, filters...))
 }
 
 // Overwrites any validators previously added with AddValidator
 func (f *Flow) WithValidators(vs ...gitops.Validator) {
 	f.Processors.Validators = vs
 }
+
+func (f *Flow) AddSetValidator(v gitops.SetValidator) {
+	f.Processors.SetValidators = append(f.Processors.SetValidators, v)
+}
+
+// Overwrites any set validators previously added with AddSetValidator
+func (f *Flow) WithSetValidators(vs ...gitops.SetValidator) {
+	f.Processors.SetValidators = vs
+}
+
+// Promotes validation warnings to errors for the given environments
+func (f *Flow) PromoteWarnings(environments ...string) {
+	f.Processors.WarningsAsErrors = append(f.Processors.WarningsAsErrors, environments...)
+}
+
+// Sets the number of files mutated or validated in parallel
+func (f *Flow) WithConcurrency(n int) {
+	f.Processors.Concurrency = n
+}
@@ -0,0 +1,42 @@
+package gitops
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Describes the file being processed by a Mutator or Validator
+type FileContext struct {
+	Path        string // Slash-separated path relative to the manifests directory
+	Size        int64  // Size in bytes of the file before any mutation
+	ContentType string // MIME type detected from the file extension and content, e.g. application/yaml
+}
+
+var contentTypesByExtension = map[string]string{
+	".yaml": "application/yaml",
+	".yml":  "application/yaml",
+	".json": "application/json",
+	".toml": "application/toml",
+	".md":   "text/markdown; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+}
+
+// Detects the content type of a file from its extension, falling back to sniffing up to the first 512 bytes of its
+// content
+func DetectContentType(name string, head []byte) string {
+	contentType, ok := contentTypesByExtension[strings.ToLower(path.Ext(name))]
+	if ok {
+		return contentType
+	}
+
+	return http.DetectContentType(head)
+}
+
+// Returns whether the content type describes text, as opposed to binary data
+func IsTextContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		contentType == "application/yaml" ||
+		contentType == "application/json" ||
+		contentType == "application/toml"
+}
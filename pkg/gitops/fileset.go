@@ -0,0 +1,59 @@
+package gitops
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+type dirFileSet struct {
+	fs.FS
+	dir string
+}
+
+// Returns a FileSet for the files in a directory on the local filesystem
+func NewDirFileSet(dir string) FileSet {
+	return &dirFileSet{
+		FS:  os.DirFS(dir),
+		dir: dir,
+	}
+}
+
+func (d *dirFileSet) WriteFile(name string, data []byte) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	fullPath := filepath.Join(d.dir, filepath.FromSlash(name))
+	err := os.MkdirAll(filepath.Dir(fullPath), 0755)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fullPath, data, 0644)
+}
+
+func (d *dirFileSet) Remove(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	err := os.Remove(filepath.Join(d.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		entries, err := os.ReadDir(filepath.Join(d.dir, filepath.FromSlash(dir)))
+		if err != nil || len(entries) > 0 {
+			break
+		}
+		err = os.Remove(filepath.Join(d.dir, filepath.FromSlash(dir)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
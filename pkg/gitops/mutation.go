@@ -3,9 +3,24 @@ package gitops
 import (
 	"context"
 	"io"
+	"io/fs"
 )
 
 type Mutator interface {
 	GetTitle() string
-	MutateFile(ctx context.Context, request *Request, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error
+	MutateFile(ctx context.Context, request *Request, fileContext *FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error
+}
+
+// A FileSetMutator receives the full set of manifests at once and may add, remove or rename files. File set mutators run
+// after all Mutators. Paths within files are relative to the manifests directory.
+type FileSetMutator interface {
+	GetTitle() string
+	MutateFiles(ctx context.Context, request *Request, files FileSet, sendMsg func(string)) error
+}
+
+// A FileSet is a writable fs.FS. Names are slash-separated paths as accepted by fs.ValidPath.
+type FileSet interface {
+	fs.FS
+	WriteFile(name string, data []byte) error // Creates or truncates a file, creating parent directories as needed
+	Remove(name string) error                 // Removes a file along with any parent directories left empty
 }
@@ -0,0 +1,216 @@
+package mutators
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This mutator rewrites YAML manifests in a canonical form so that equivalent renders produce identical files
+type CanonicalFormat struct {
+	PreserveDocumentOrder bool // Whether to keep documents in their rendered order instead of sorting them by kind, namespace and name.
+	StripComments         bool // Whether to remove all comments. Helm "# Source:" comments are removed either way.
+}
+
+// Keys placed first in a Kubernetes object, in this order. Remaining keys follow alphabetically.
+var objectKeyOrder = []string{
+	"apiVersion",
+	"kind",
+	"metadata",
+	"type",
+	"immutable",
+	"data",
+	"stringData",
+	"binaryData",
+	"spec",
+	"rules",
+	"subjects",
+	"roleRef",
+	"webhooks",
+	"status",
+}
+
+var metadataKeyOrder = []string{
+	"name",
+	"generateName",
+	"namespace",
+	"labels",
+	"annotations",
+}
+
+// Keys placed first in any other mapping, e.g. containers, ports and volumes
+var nestedKeyOrder = []string{
+	"name",
+}
+
+const helmSourceCommentPrefix = "# Source:"
+
+func (c *CanonicalFormat) GetTitle() string {
+	return "Canonical formatting"
+}
+
+func (c *CanonicalFormat) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	// Files which are not YAML, such as JSON manifests, are passed through unchanged
+	extension := strings.ToLower(path.Ext(fileContext.Path))
+	if fileContext.ContentType != "application/yaml" && extension != ".yaml" && extension != ".yml" {
+		return nil
+	}
+
+	input, err := io.ReadAll(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(bytes.NewReader(input))
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+	// The MAC of a SOPS-encrypted file covers its values in order, so reordering them would make it undecryptable
+	if hasSOPSMetadata(documents) {
+		return nil
+	}
+
+	canonical := make([]*yaml.Node, 0, len(documents))
+	for _, doc := range documents {
+		c.formatNode(doc)
+		if len(doc.Content) == 0 || isNullNode(doc.Content[0]) {
+			continue
+		}
+
+		obj := doc.Content[0]
+		if yamlUtil.IsKubernetesObject(obj) {
+			sortMapping(obj, objectKeyOrder)
+			sortMapping(yamlUtil.GetNode(obj, "metadata"), metadataKeyOrder)
+		}
+		canonical = append(canonical, doc)
+	}
+	if len(canonical) == 0 {
+		return nil
+	}
+
+	if !c.PreserveDocumentOrder {
+		slices.SortStableFunc(canonical, compareDocuments)
+	}
+
+	output := &bytes.Buffer{}
+	err = yamlUtil.EncodeDocuments(output, canonical)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if bytes.Equal(input, output.Bytes()) {
+		return nil
+	}
+
+	_, err = outputFile.Write(output.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// Normalises comments, styles and key order of a node and all of its descendants. Sequences keep their order as it
+// is significant.
+func (c *CanonicalFormat) formatNode(node *yaml.Node) {
+	if c.StripComments {
+		node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	} else {
+		node.HeadComment = stripHelmSourceComments(node.HeadComment)
+		node.LineComment = stripHelmSourceComments(node.LineComment)
+		node.FootComment = stripHelmSourceComments(node.FootComment)
+	}
+
+	// Explicit tags such as !!binary are kept, while quoting and flow styles are left to the encoder
+	node.Style &= yaml.TaggedStyle
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+		node.Style |= yaml.LiteralStyle
+	}
+
+	for _, child := range node.Content {
+		c.formatNode(child)
+	}
+	if node.Kind == yaml.MappingNode {
+		sortMapping(node, nestedKeyOrder)
+	}
+}
+
+// Sorts the key-value pairs of a mapping, placing keys found in order first and all others alphabetically
+func sortMapping(mapping *yaml.Node, order []string) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{mapping.Content[i], mapping.Content[i+1]})
+	}
+
+	rank := func(key string) int {
+		index := slices.Index(order, key)
+		if index == -1 {
+			return len(order)
+		}
+		return index
+	}
+	slices.SortStableFunc(pairs, func(a, b [2]*yaml.Node) int {
+		return cmp.Or(
+			cmp.Compare(rank(a[0].Value), rank(b[0].Value)),
+			cmp.Compare(a[0].Value, b[0].Value),
+		)
+	})
+
+	mapping.Content = mapping.Content[:0]
+	for _, pair := range pairs {
+		mapping.Content = append(mapping.Content, pair[0], pair[1])
+	}
+}
+
+// Orders Kubernetes objects by kind, namespace and name. Other documents keep their relative order after them.
+func compareDocuments(a, b *yaml.Node) int {
+	objA, objB := a.Content[0], b.Content[0]
+	isObjA, isObjB := yamlUtil.IsKubernetesObject(objA), yamlUtil.IsKubernetesObject(objB)
+	if !isObjA || !isObjB {
+		switch {
+		case isObjA:
+			return -1
+		case isObjB:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	metadataA, metadataB := yamlUtil.GetNode(objA, "metadata"), yamlUtil.GetNode(objB, "metadata")
+
+	return cmp.Or(
+		cmp.Compare(yamlUtil.GetString(objA, "kind"), yamlUtil.GetString(objB, "kind")),
+		cmp.Compare(yamlUtil.GetString(metadataA, "namespace"), yamlUtil.GetString(metadataB, "namespace")),
+		cmp.Compare(yamlUtil.GetString(metadataA, "name"), yamlUtil.GetString(metadataB, "name")),
+	)
+}
+
+func stripHelmSourceComments(comment string) string {
+	if comment == "" {
+		return comment
+	}
+
+	lines := strings.Split(comment, "\n")
+	lines = slices.DeleteFunc(lines, func(line string) bool {
+		return strings.HasPrefix(strings.TrimSpace(line), helmSourceCommentPrefix)
+	})
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isNullNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
@@ -0,0 +1,152 @@
+package mutators
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys are out of canonical order and documents are not sorted by name, so formatting would rewrite the file
+const sopsTestSecrets = `kind: Secret
+apiVersion: v1
+stringData:
+  password: hunter2
+  username: admin
+metadata:
+  name: b-credentials
+---
+metadata:
+  name: a-credentials
+apiVersion: v1
+kind: Secret
+data:
+  token: c2VjcmV0
+`
+
+func TestCanonicalFormatLeavesSOPSEncryptedFilesUnchanged(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("failed to generate age identity: %s", err)
+	}
+	request := &gitops.Request{Environment: "dev"}
+	fileContext := &gitops.FileContext{Path: "secrets.yaml", ContentType: "application/yaml"}
+
+	sops := &SOPS{
+		MapRecipientsFn: func(environment string) ([]string, error) {
+			return []string{identity.Recipient().String()}, nil
+		},
+	}
+	encrypted := &bytes.Buffer{}
+	err = sops.MutateFile(context.Background(), request, fileContext, strings.NewReader(sopsTestSecrets), encrypted, func(string) {})
+	if err != nil {
+		t.Fatalf("SOPS.MutateFile() error = %s", err)
+	}
+
+	formatted := &bytes.Buffer{}
+	err = (&CanonicalFormat{}).MutateFile(context.Background(), request, fileContext, bytes.NewReader(encrypted.Bytes()), formatted, func(string) {})
+	if err != nil {
+		t.Fatalf("CanonicalFormat.MutateFile() error = %s", err)
+	}
+	if formatted.Len() > 0 {
+		t.Fatalf("CanonicalFormat rewrote an encrypted file:\n%s", formatted)
+	}
+
+	values := decryptSOPSFile(t, encrypted.Bytes(), identity)
+	want := map[string]string{
+		"0:stringData:password": "hunter2",
+		"0:stringData:username": "admin",
+		"1:data:token":          "c2VjcmV0",
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("decrypted %s = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestCanonicalFormatFormatsPlainTextSecrets(t *testing.T) {
+	formatted := &bytes.Buffer{}
+	err := (&CanonicalFormat{}).MutateFile(context.Background(), &gitops.Request{}, &gitops.FileContext{Path: "secrets.yaml", ContentType: "application/yaml"}, strings.NewReader(sopsTestSecrets), formatted, func(string) {})
+	if err != nil {
+		t.Fatalf("CanonicalFormat.MutateFile() error = %s", err)
+	}
+
+	if !strings.HasPrefix(formatted.String(), "apiVersion: v1\nkind: Secret\nmetadata:\n  name: a-credentials\n") {
+		t.Errorf("CanonicalFormat did not format the plain text file:\n%s", formatted)
+	}
+}
+
+// Decrypts a SOPS file as the sops CLI does, failing the test if the MAC over the values of all documents does not
+// match. Returns the plain text of the encrypted values keyed by document index and key path.
+func decryptSOPSFile(t *testing.T, content []byte, identity age.Identity) map[string]string {
+	t.Helper()
+
+	documents, err := yamlUtil.DecodeDocuments(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to parse encrypted file: %s", err)
+	}
+	var metadata sopsMetadata
+	err = yamlUtil.GetNode(documents[0].Content[0], sopsMetadataKey).Decode(&metadata)
+	if err != nil {
+		t.Fatalf("failed to decode sops metadata of the first document: %s", err)
+	}
+	if len(metadata.Age) == 0 {
+		t.Fatal("sops metadata has no age recipients")
+	}
+	dataKey, err := unwrapDataKey(metadata.Age[0].EncryptedKey, []age.Identity{identity})
+	if err != nil {
+		t.Fatalf("failed to decrypt data key: %s", err)
+	}
+	pattern := regexp.MustCompile(metadata.EncryptedRegex)
+
+	values := make(map[string]string)
+	hash := sha512.New()
+	for i, doc := range documents {
+		err := walkSOPSValues(doc.Content[0], nil, false, pattern, func(node *yaml.Node, path []string, encrypt bool) error {
+			if path[0] == sopsMetadataKey {
+				return nil
+			}
+			if !encrypt {
+				var value any
+				err := node.Decode(&value)
+				if err != nil || value == nil {
+					return err
+				}
+				plaintext, _, err := sopsPlaintext(value)
+				hash.Write(plaintext)
+				return err
+			}
+
+			plaintext, _, err := sopsDecrypt(node.Value, dataKey, strings.Join(path, ":")+":")
+			if err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+			}
+			hash.Write(plaintext)
+			values[fmt.Sprintf("%d:%s", i, strings.Join(path, ":"))] = string(plaintext)
+
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("document %d: %s", i, err)
+		}
+	}
+
+	mac, _, err := sopsDecrypt(metadata.MAC, dataKey, metadata.LastModified)
+	if err != nil {
+		t.Fatalf("failed to decrypt MAC: %s", err)
+	}
+	if string(mac) != fmt.Sprintf("%X", hash.Sum(nil)) {
+		t.Fatal("MAC does not match the values of the file")
+	}
+
+	return values
+}
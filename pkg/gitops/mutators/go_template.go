@@ -0,0 +1,214 @@
+package mutators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	"gopkg.in/yaml.v3"
+)
+
+// This mutator renders each file as a Go text/template
+type GoTemplate struct {
+	LeftDelim  string                                     // The left action delimiter. Defaults to {{.
+	RightDelim string                                     // The right action delimiter. Defaults to }}.
+	Strict     bool                                       // Whether to fail when a template references a missing map key instead of rendering it as empty.
+	Funcs      template.FuncMap                           // Additional template functions. These take precedence over the built-in functions.
+	GetData    func(request *gitops.Request) (any, error) // An optional function to provide additional data, available as .Data.
+}
+
+// The data available to templates rendered by the GoTemplate mutator
+type TemplateData struct {
+	Environment      string
+	AppName          string
+	UpdateIdentifier string
+	DryRun           bool
+	Metadata         map[string]any
+	Source           TemplateSource
+	Data             any
+}
+
+type TemplateSource struct {
+	Repository string
+	CommitSHA  string
+	Actor      string
+	Attributes map[string]any
+}
+
+// The function which renders nil values as empty. It is added after any custom functions so that it cannot be replaced.
+const emptyIfNilFunc = "emptyIfNil"
+
+// Functions which expose the server environment are left out of the helper library
+var excludedTemplateFuncs = []string{"env", "expandenv"}
+
+func (g *GoTemplate) GetTitle() string {
+	return "Go template"
+}
+
+func (g *GoTemplate) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	inputData, err := io.ReadAll(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	missingKey := "missingkey=zero"
+	if g.Strict {
+		missingKey = "missingkey=error"
+	}
+	// Naming the template after the file makes errors read as path:line:column
+	tmpl, err := template.New(fileContext.Path).
+		Delims(g.LeftDelim, g.RightDelim).
+		Option(missingKey).
+		Funcs(templateFuncs(g.Funcs)).
+		Parse(string(inputData))
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	for _, t := range tmpl.Templates() {
+		emptyNilValues(t.Tree, t.Tree.Root)
+	}
+
+	data, err := g.templateData(request)
+	if err != nil {
+		return fmt.Errorf("failed to get template data: %w", err)
+	}
+
+	var b strings.Builder
+	err = tmpl.Execute(&b, data)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	_, err = io.WriteString(outputFile, b.String())
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+func (g *GoTemplate) templateData(request *gitops.Request) (*TemplateData, error) {
+	data := &TemplateData{
+		Environment:      request.Environment,
+		AppName:          request.AppName,
+		UpdateIdentifier: request.UpdateIdentifier,
+		DryRun:           request.DryRun,
+		Metadata:         request.Metadata,
+	}
+	if request.Source != nil {
+		if request.Source.Repository != nil {
+			data.Source.Repository = request.Source.Repository.URL
+		}
+		if request.Source.Metadata != nil {
+			data.Source.CommitSHA = request.Source.Metadata.CommitSHA
+			data.Source.Actor = request.Source.Metadata.Actor
+			data.Source.Attributes = request.Source.Metadata.Attributes
+		}
+	}
+
+	if g.GetData != nil {
+		var err error
+		data.Data, err = g.GetData(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// Returns the sprig function library along with the YAML helpers familiar from Helm
+func templateFuncs(funcs template.FuncMap) template.FuncMap {
+	funcMap := sprig.TxtFuncMap()
+	for _, name := range excludedTemplateFuncs {
+		delete(funcMap, name)
+	}
+
+	funcMap["toYaml"] = toYAML
+	funcMap["fromYaml"] = fromYAML
+	funcMap["required"] = required
+
+	for name, fn := range funcs {
+		funcMap[name] = fn
+	}
+	funcMap[emptyIfNilFunc] = emptyIfNil
+
+	return funcMap
+}
+
+// Appends emptyIfNil to the pipeline of every action which prints a value. Even with missingkey=zero, a missing key of a
+// map[string]any is nil and would print as "<no value>".
+func emptyNilValues(tree *parse.Tree, node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			emptyNilValues(tree, n)
+		}
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) > 0 {
+			return
+		}
+		identifier := parse.NewIdentifier(emptyIfNilFunc).SetTree(tree).SetPos(node.Pos)
+		node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: node.Pos, Args: []parse.Node{identifier}})
+	case *parse.IfNode:
+		emptyNilValues(tree, node.List)
+		emptyNilValues(tree, node.ElseList)
+	case *parse.RangeNode:
+		emptyNilValues(tree, node.List)
+		emptyNilValues(tree, node.ElseList)
+	case *parse.WithNode:
+		emptyNilValues(tree, node.List)
+		emptyNilValues(tree, node.ElseList)
+	}
+}
+
+func emptyIfNil(value any) any {
+	if value == nil {
+		return ""
+	}
+
+	return value
+}
+
+func toYAML(value any) (string, error) {
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	err := encoder.Encode(value)
+	if err != nil {
+		return "", err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+func fromYAML(value string) (map[string]any, error) {
+	result := make(map[string]any)
+	err := yaml.Unmarshal([]byte(value), &result)
+
+	return result, err
+}
+
+func required(message string, value any) (any, error) {
+	if value == nil {
+		return nil, errors.New(message)
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, errors.New(message)
+	}
+
+	return value, nil
+}
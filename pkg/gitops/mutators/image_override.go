@@ -0,0 +1,163 @@
+package mutators
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"text/template"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+)
+
+// This mutator rewrites the container images of every workload according to rules matching the image repository
+type ImageOverride struct {
+	Rules []ImageOverrideRule
+
+	once      sync.Once
+	templates []imageOverrideTemplates
+	parseErr  error
+}
+
+// The Repository, Tag and Digest fields of a rule are Go templates evaluated against the request, e.g. `{{ index .Metadata "image.tag" }}`.
+// Fields which render empty leave that part of the image untouched.
+type ImageOverrideRule struct {
+	Match      string // Glob pattern matched against the fully qualified image repository, e.g. "ghcr.io/example/*" or "docker.io/library/nginx"
+	Repository string // Replaces the image repository
+	Tag        string // Replaces the image tag. Any existing digest is removed unless Digest is also set.
+	Digest     string // Pins the image to a digest, e.g. "sha256:..."
+}
+
+type imageOverrideTemplates struct {
+	repository *template.Template
+	tag        *template.Template
+	digest     *template.Template
+}
+
+func (i *ImageOverride) GetTitle() string {
+	return "Image override"
+}
+
+func (i *ImageOverride) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	i.once.Do(i.parse)
+	if i.parseErr != nil {
+		return i.parseErr
+	}
+
+	overrides := make([]ImageOverrideRule, len(i.Rules))
+	for n, rule := range i.Rules {
+		rendered, err := renderAll(map[string]*template.Template{
+			"repository": i.templates[n].repository,
+			"tag":        i.templates[n].tag,
+			"digest":     i.templates[n].digest,
+		}, request)
+		if err != nil {
+			return fmt.Errorf("failed to render image override rule for %s: %w", rule.Match, err)
+		}
+		overrides[n] = ImageOverrideRule{
+			Match:      rule.Match,
+			Repository: rendered["repository"],
+			Tag:        rendered["tag"],
+			Digest:     rendered["digest"],
+		}
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+
+	mutated := false
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || !yamlUtil.IsKubernetesObject(doc.Content[0]) {
+			continue
+		}
+		obj := doc.Content[0]
+
+		for _, podSpec := range yamlUtil.PodSpecs(obj) {
+			for _, container := range yamlUtil.Containers(podSpec) {
+				image := yamlUtil.GetString(container, "image")
+				if image == "" {
+					continue
+				}
+
+				newImage := overrideImage(image, overrides)
+				if newImage == image {
+					continue
+				}
+
+				sendMsg(fmt.Sprintf("container %q in %s: %s -> %s", yamlUtil.GetString(container, "name"), yamlUtil.ResourceID(obj), image, newImage))
+				yamlUtil.SetMappingValue(container, "image", newImage)
+				mutated = true
+			}
+		}
+	}
+
+	if !mutated {
+		return nil
+	}
+
+	err = yamlUtil.EncodeDocuments(outputFile, documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return nil
+}
+
+func (i *ImageOverride) parse() {
+	i.templates = make([]imageOverrideTemplates, len(i.Rules))
+	for n, rule := range i.Rules {
+		// Matching against an empty name reports a malformed pattern, which would otherwise never match
+		_, err := path.Match(rule.Match, "")
+		if err != nil {
+			i.parseErr = fmt.Errorf("invalid image override pattern %q: %w", rule.Match, err)
+			return
+		}
+
+		templates, err := parseAll("image override", map[string]string{
+			"repository": rule.Repository,
+			"tag":        rule.Tag,
+			"digest":     rule.Digest,
+		})
+		if err != nil {
+			i.parseErr = fmt.Errorf("invalid image override rule for %s: %w", rule.Match, err)
+			return
+		}
+		i.templates[n] = imageOverrideTemplates{
+			repository: templates["repository"],
+			tag:        templates["tag"],
+			digest:     templates["digest"],
+		}
+	}
+}
+
+// Applies the first rule matching the image
+func overrideImage(image string, overrides []ImageOverrideRule) string {
+	ref := yamlUtil.ParseImageReference(image)
+	name, tag, digest := yamlUtil.SplitImage(image)
+
+	for _, override := range overrides {
+		// Patterns are validated when the rules are parsed
+		if ok, _ := path.Match(override.Match, ref.Name()); !ok {
+			continue
+		}
+
+		if override.Repository != "" {
+			name = override.Repository
+		}
+		if override.Tag != "" {
+			tag = override.Tag
+			digest = ""
+		}
+		if override.Digest != "" {
+			digest = override.Digest
+		}
+
+		return yamlUtil.JoinImage(name, tag, digest)
+	}
+
+	return image
+}
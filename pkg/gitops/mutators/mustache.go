@@ -19,7 +19,7 @@ func (m *Mustache) GetTitle() string {
 	return "Mustache template"
 }
 
-func (m *Mustache) MutateFile(ctx context.Context, request *gitops.Request, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+func (m *Mustache) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
 	inputData, err := io.ReadAll(inputFile)
 	if err != nil {
 		return fmt.Errorf("failed to read input file: %w", err)
@@ -30,7 +30,9 @@ func (m *Mustache) MutateFile(ctx context.Context, request *gitops.Request, inpu
 		return fmt.Errorf("failed to get template data: %w", err)
 	}
 	templated, err := mustache.Render(string(inputData), data)
-	fmt.Println(templated)
+	if err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
 
 	bytesWritten, err := io.WriteString(outputFile, templated)
 	slog.Debug("written to output file", "bytesWritten", bytesWritten)
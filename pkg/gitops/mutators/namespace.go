@@ -0,0 +1,107 @@
+package mutators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+)
+
+// This mutator sets metadata.namespace on every namespaced resource
+type Namespace struct {
+	MapNamespaceFn     func(environment, appName string) (string, error) // A function to map an environment and application to a namespace. Return "" to leave namespaces untouched.
+	ClusterScopedKinds []string                                          // Kinds to treat as cluster-scoped in addition to the built-in Kubernetes kinds, e.g. custom resources.
+	Strict             bool                                              // Whether to fail when a manifest already names a different namespace instead of overriding it.
+}
+
+var clusterScopedKinds = []string{
+	"APIService",
+	"CSIDriver",
+	"CSINode",
+	"CertificateSigningRequest",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"ComponentStatus",
+	"CustomResourceDefinition",
+	"FlowSchema",
+	"IngressClass",
+	"MutatingAdmissionPolicy",
+	"MutatingAdmissionPolicyBinding",
+	"MutatingWebhookConfiguration",
+	"Namespace",
+	"Node",
+	"PersistentVolume",
+	"PriorityClass",
+	"PriorityLevelConfiguration",
+	"RuntimeClass",
+	"StorageClass",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+	"ValidatingWebhookConfiguration",
+	"VolumeAttachment",
+}
+
+func (n *Namespace) GetTitle() string {
+	return "Namespace enforcement"
+}
+
+func (n *Namespace) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	if n.MapNamespaceFn == nil {
+		return errors.New("a namespace mapping function is required")
+	}
+	namespace, err := n.MapNamespaceFn(request.Environment, request.AppName)
+	if err != nil {
+		return fmt.Errorf("failed to map request to a namespace: %w", err)
+	}
+	if namespace == "" {
+		return nil
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+
+	mutated := false
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || !yamlUtil.IsKubernetesObject(doc.Content[0]) {
+			continue
+		}
+		obj := doc.Content[0]
+
+		kind := yamlUtil.GetString(obj, "kind")
+		if slices.Contains(clusterScopedKinds, kind) || slices.Contains(n.ClusterScopedKinds, kind) {
+			continue
+		}
+
+		metadata := yamlUtil.GetOrCreateMap(obj, "metadata")
+		current := yamlUtil.GetString(metadata, "namespace")
+		if current == namespace {
+			continue
+		}
+		if current != "" {
+			if n.Strict {
+				return fmt.Errorf("%s is in namespace %q but must be in %q", yamlUtil.ResourceID(obj), current, namespace)
+			}
+			sendMsg(fmt.Sprintf("overriding namespace of %s with %q", yamlUtil.ResourceID(obj), namespace))
+		}
+
+		yamlUtil.SetMappingValue(metadata, "namespace", namespace)
+		mutated = true
+	}
+
+	if !mutated {
+		return nil
+	}
+
+	err = yamlUtil.EncodeDocuments(outputFile, documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return nil
+}
@@ -16,7 +16,7 @@ func (_ *NewLineEOF) GetTitle() string {
 	return "New Line EOF"
 }
 
-func (_ *NewLineEOF) MutateFile(ctx context.Context, request *gitops.Request, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+func (_ *NewLineEOF) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
 	buf := &bytes.Buffer{}
 	_, err := io.Copy(buf, inputFile)
 	if err != nil {
@@ -0,0 +1,219 @@
+package mutators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"text/template"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This mutator applies RFC 6902 JSON patches and strategic-merge-style patches to the Kubernetes objects they target
+type Patches struct {
+	PatchSetFn func(request *gitops.Request) (*PatchSet, error) // A function to select the patch set for a request. Return nil to apply no patches.
+}
+
+type PatchSet struct {
+	Patches []Patch `yaml:"patches"`
+}
+
+// A patch holds either a strategic-merge-style patch, a list of JSON patch operations, or both. Merge patches are applied first.
+type Patch struct {
+	Target PatchTarget          `yaml:"target"`
+	Merge  yaml.Node            `yaml:"merge"`
+	JSON   []JSONPatchOperation `yaml:"json"`
+}
+
+// Selects objects by glob patterns. Empty fields match every object.
+type PatchTarget struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type JSONPatchOperation struct {
+	Op    string    `yaml:"op"`
+	Path  string    `yaml:"path"`
+	From  string    `yaml:"from"`
+	Value yaml.Node `yaml:"value"`
+}
+
+func ParsePatchSet(data []byte) (*PatchSet, error) {
+	var patchSet PatchSet
+	err := yaml.Unmarshal(data, &patchSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch set: %w", err)
+	}
+	err = patchSet.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &patchSet, nil
+}
+
+// Checks that the target patterns of every patch are well-formed, since a malformed pattern would never match
+func (s *PatchSet) Validate() error {
+	for i, patch := range s.Patches {
+		for _, pattern := range []string{patch.Target.Kind, patch.Target.Name, patch.Target.Namespace} {
+			_, err := path.Match(pattern, "")
+			if err != nil {
+				return fmt.Errorf("invalid target pattern %q of patch %d: %w", pattern, i, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func LoadPatchSet(filename string) (*PatchSet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch set: %w", err)
+	}
+
+	return ParsePatchSet(data)
+}
+
+// Selects a patch set configured on the server by the environment of the request
+func PatchSetsByEnvironment(patchSets map[string]*PatchSet) func(request *gitops.Request) (*PatchSet, error) {
+	return func(request *gitops.Request) (*PatchSet, error) {
+		return patchSets[request.Environment], nil
+	}
+}
+
+// Loads the patch set for a request from the configuration repository. The path is a Go template evaluated against the request
+// relative to the repository root, e.g. "patches/{{ .Environment }}.yaml". No patches are applied if the file does not exist.
+func PatchSetFromRepository(pathTemplate string) func(request *gitops.Request) (*PatchSet, error) {
+	tmpl, parseErr := template.New("path").Option("missingkey=zero").Parse(pathTemplate)
+
+	var mu sync.Mutex
+	cache := make(map[*gitops.Request]*PatchSet)
+
+	return func(request *gitops.Request) (*PatchSet, error) {
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse patch set path template: %w", parseErr)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if patchSet, ok := cache[request]; ok {
+			return patchSet, nil
+		}
+
+		relativePath, err := renderTemplate(tmpl, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render patch set path: %w", err)
+		}
+
+		patchSet, err := LoadPatchSet(filepath.Join(request.Paths.RepositoryDir, filepath.Clean("/"+relativePath)))
+		if errors.Is(err, fs.ErrNotExist) {
+			patchSet = nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// Only the most recent request is cached as each request is processed once
+		clear(cache)
+		cache[request] = patchSet
+
+		return patchSet, nil
+	}
+}
+
+func (p *Patches) GetTitle() string {
+	return "Patches"
+}
+
+func (p *Patches) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	if p.PatchSetFn == nil {
+		return errors.New("a patch set function is required")
+	}
+	patchSet, err := p.PatchSetFn(request)
+	if err != nil {
+		return fmt.Errorf("failed to get patch set: %w", err)
+	}
+	if patchSet == nil || len(patchSet.Patches) == 0 {
+		return nil
+	}
+	// Patch sets built in code are not validated when loaded
+	err = patchSet.Validate()
+	if err != nil {
+		return err
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+
+	mutated := false
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || !yamlUtil.IsKubernetesObject(doc.Content[0]) {
+			continue
+		}
+		resource := yamlUtil.ResourceID(doc.Content[0])
+
+		for i, patch := range patchSet.Patches {
+			if !patch.Target.matches(doc.Content[0]) {
+				continue
+			}
+
+			if !patch.Merge.IsZero() {
+				err := applyMergePatch(doc.Content[0], &patch.Merge)
+				if err != nil {
+					return fmt.Errorf("failed to apply merge patch %d to %s: %w", i, resource, err)
+				}
+			}
+
+			for _, op := range patch.JSON {
+				err := applyJSONPatchOperation(doc, op)
+				if err != nil {
+					return fmt.Errorf("failed to apply JSON patch %d to %s: %s %s: %w", i, resource, op.Op, op.Path, err)
+				}
+			}
+
+			sendMsg(fmt.Sprintf("applied patch %d to %s", i, resource))
+			mutated = true
+		}
+	}
+
+	if !mutated {
+		return nil
+	}
+
+	err = yamlUtil.EncodeDocuments(outputFile, documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return nil
+}
+
+func (t *PatchTarget) matches(obj *yaml.Node) bool {
+	metadata := yamlUtil.GetNode(obj, "metadata")
+
+	return globMatch(t.Kind, yamlUtil.GetString(obj, "kind")) &&
+		globMatch(t.Name, yamlUtil.GetString(metadata, "name")) &&
+		globMatch(t.Namespace, yamlUtil.GetString(metadata, "namespace"))
+}
+
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	// Patterns are validated before patches are applied
+	ok, _ := path.Match(pattern, value)
+
+	return ok
+}
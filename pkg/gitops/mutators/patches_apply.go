@@ -0,0 +1,381 @@
+package mutators
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys by which list items are merged, in order of preference. Lists without a merge key are replaced.
+var mergeKeys = []string{"name", "containerPort", "mountPath", "devicePath", "ip"}
+
+const patchDirective = "$patch"
+
+// Applies a strategic-merge-style patch. Mappings are merged recursively, null values remove keys, and lists of mappings are
+// merged by a well-known key such as "name". The "$patch" directive may be set to "replace" on a mapping to replace it or to
+// "delete" on a list item to remove it.
+func applyMergePatch(dst, patch *yaml.Node) error {
+	if patch.Kind == yaml.DocumentNode && len(patch.Content) > 0 {
+		patch = patch.Content[0]
+	}
+	if dst.Kind != yaml.MappingNode || patch.Kind != yaml.MappingNode {
+		return fmt.Errorf("merge patches must be mappings")
+	}
+
+	for i := 0; i+1 < len(patch.Content); i += 2 {
+		key := patch.Content[i].Value
+		value := patch.Content[i+1]
+		if key == patchDirective {
+			continue
+		}
+
+		index := mappingIndex(dst, key)
+
+		if value.Tag == "!!null" {
+			if index >= 0 {
+				yamlUtil.DeleteMappingKeysByIndices(dst, []int{index})
+			}
+			continue
+		}
+
+		if index < 0 {
+			dst.Content = append(dst.Content, cloneNode(patch.Content[i]), stripDirectives(cloneNode(value)))
+			continue
+		}
+
+		existing := dst.Content[index+1]
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode && yamlUtil.GetString(value, patchDirective) != "replace":
+			err := applyMergePatch(existing, value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		case existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && listMergeKey(value) != "":
+			err := mergeLists(existing, value, listMergeKey(value))
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		default:
+			replaceNode(existing, stripDirectives(cloneNode(value)))
+		}
+	}
+
+	return nil
+}
+
+func mergeLists(dst, patch *yaml.Node, mergeKey string) error {
+	for _, item := range patch.Content {
+		id := yamlUtil.GetString(item, mergeKey)
+		index := -1
+		for i, existing := range dst.Content {
+			if existing.Kind == yaml.MappingNode && yamlUtil.GetString(existing, mergeKey) == id {
+				index = i
+				break
+			}
+		}
+
+		if yamlUtil.GetString(item, patchDirective) == "delete" {
+			if index >= 0 {
+				dst.Content = append(dst.Content[:index], dst.Content[index+1:]...)
+			}
+			continue
+		}
+
+		if index < 0 {
+			dst.Content = append(dst.Content, stripDirectives(cloneNode(item)))
+			continue
+		}
+
+		err := applyMergePatch(dst.Content[index], item)
+		if err != nil {
+			return fmt.Errorf("%s=%s: %w", mergeKey, id, err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the key by which a list of mappings should be merged, or "" if it should be replaced
+func listMergeKey(list *yaml.Node) string {
+	if len(list.Content) == 0 {
+		return ""
+	}
+
+	for _, key := range mergeKeys {
+		all := true
+		for _, item := range list.Content {
+			if item.Kind != yaml.MappingNode || yamlUtil.GetNode(item, key) == nil {
+				all = false
+				break
+			}
+		}
+		if all {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// Applies a single RFC 6902 operation to a document node
+func applyJSONPatchOperation(doc *yaml.Node, op JSONPatchOperation) error {
+	switch op.Op {
+	case "add":
+		if op.Value.IsZero() {
+			return fmt.Errorf("missing value")
+		}
+		return jsonPointerAdd(doc, op.Path, cloneNode(documentContent(&op.Value)))
+	case "remove":
+		_, err := jsonPointerRemove(doc, op.Path)
+		return err
+	case "replace":
+		if op.Value.IsZero() {
+			return fmt.Errorf("missing value")
+		}
+		existing, err := jsonPointerGet(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		replaceNode(existing, cloneNode(documentContent(&op.Value)))
+		return nil
+	case "move":
+		value, err := jsonPointerRemove(doc, op.From)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "copy":
+		value, err := jsonPointerGet(doc, op.From)
+		if err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+		return jsonPointerAdd(doc, op.Path, cloneNode(value))
+	case "test":
+		if op.Value.IsZero() {
+			return fmt.Errorf("missing value")
+		}
+		existing, err := jsonPointerGet(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		var actual, expected any
+		if err := existing.Decode(&actual); err != nil {
+			return err
+		}
+		if err := documentContent(&op.Value).Decode(&expected); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+func splitJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func jsonPointerChild(node *yaml.Node, token string) (*yaml.Node, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		child := yamlUtil.GetNode(node, token)
+		if child == nil {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		return child, nil
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index >= len(node.Content) {
+			return nil, fmt.Errorf("index %q out of range", token)
+		}
+		return node.Content[index], nil
+	default:
+		return nil, fmt.Errorf("cannot traverse into scalar with %q", token)
+	}
+}
+
+func jsonPointerGet(doc *yaml.Node, pointer string) (*yaml.Node, error) {
+	tokens, err := splitJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	node := documentContent(doc)
+	for _, token := range tokens {
+		node, err = jsonPointerChild(node, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
+func jsonPointerParent(doc *yaml.Node, pointer string) (*yaml.Node, string, error) {
+	tokens, err := splitJSONPointer(pointer)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", fmt.Errorf("the document root cannot be the target of this operation")
+	}
+
+	parent := documentContent(doc)
+	for _, token := range tokens[:len(tokens)-1] {
+		parent, err = jsonPointerChild(parent, token)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return parent, tokens[len(tokens)-1], nil
+}
+
+func jsonPointerAdd(doc *yaml.Node, pointer string, value *yaml.Node) error {
+	if pointer == "" {
+		replaceNode(documentContent(doc), value)
+		return nil
+	}
+
+	parent, token, err := jsonPointerParent(doc, pointer)
+	if err != nil {
+		return err
+	}
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		index := mappingIndex(parent, token)
+		if index >= 0 {
+			parent.Content[index+1] = value
+		} else {
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}, value)
+		}
+	case yaml.SequenceNode:
+		if token == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index > len(parent.Content) {
+			return fmt.Errorf("index %q out of range", token)
+		}
+		parent.Content = append(parent.Content[:index], append([]*yaml.Node{value}, parent.Content[index:]...)...)
+	default:
+		return fmt.Errorf("cannot add to a scalar")
+	}
+
+	return nil
+}
+
+func jsonPointerRemove(doc *yaml.Node, pointer string) (*yaml.Node, error) {
+	parent, token, err := jsonPointerParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parent.Kind {
+	case yaml.MappingNode:
+		index := mappingIndex(parent, token)
+		if index < 0 {
+			return nil, fmt.Errorf("key %q not found", token)
+		}
+		removed := parent.Content[index+1]
+		yamlUtil.DeleteMappingKeysByIndices(parent, []int{index})
+		return removed, nil
+	case yaml.SequenceNode:
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || index >= len(parent.Content) {
+			return nil, fmt.Errorf("index %q out of range", token)
+		}
+		removed := parent.Content[index]
+		parent.Content = append(parent.Content[:index], parent.Content[index+1:]...)
+		return removed, nil
+	default:
+		return nil, fmt.Errorf("cannot remove from a scalar")
+	}
+}
+
+func documentContent(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+
+	return node
+}
+
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Replaces the value of a node in place, keeping the comments of the original
+func replaceNode(dst, src *yaml.Node) {
+	headComment, lineComment, footComment := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	if dst.HeadComment == "" {
+		dst.HeadComment = headComment
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = lineComment
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = footComment
+	}
+}
+
+func cloneNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	clone := *node
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = cloneNode(child)
+	}
+
+	return &clone
+}
+
+// Removes "$patch" directives from a node about to be inserted
+func stripDirectives(node *yaml.Node) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if index := mappingIndex(node, patchDirective); index >= 0 {
+			yamlUtil.DeleteMappingKeysByIndices(node, []int{index})
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			stripDirectives(node.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			stripDirectives(child)
+		}
+	}
+
+	return node
+}
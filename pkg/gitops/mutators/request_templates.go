@@ -0,0 +1,48 @@
+package mutators
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+func parseAll(kind string, values map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(values))
+	for key, value := range values {
+		tmpl, err := template.New(key).Option("missingkey=zero").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s template %s: %w", kind, key, err)
+		}
+		templates[key] = tmpl
+	}
+
+	return templates, nil
+}
+
+func renderAll(templates map[string]*template.Template, request *gitops.Request) (map[string]string, error) {
+	rendered := make(map[string]string, len(templates))
+	for key, tmpl := range templates {
+		value, err := renderTemplate(tmpl, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", key, err)
+		}
+		if value != "" {
+			rendered[key] = value
+		}
+	}
+
+	return rendered, nil
+}
+
+// Renders a template against the request. Missing metadata keys render as "<no value>" and are treated as empty, as Helm does.
+func renderTemplate(tmpl *template.Template, request *gitops.Request) (string, error) {
+	var b strings.Builder
+	err := tmpl.Execute(&b, request)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(b.String(), "<no value>", ""), nil
+}
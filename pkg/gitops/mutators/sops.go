@@ -0,0 +1,581 @@
+package mutators
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This mutator encrypts files containing Kubernetes Secrets in the SOPS format using age recipients
+type SOPS struct {
+	MapRecipientsFn func(environment string) ([]string, error) // A function to map an environment to the age recipients (age1...) able to decrypt its secrets.
+	MapIdentitiesFn func(environment string) ([]string, error) // An optional function to map an environment to age identities (AGE-SECRET-KEY-1...) able to decrypt its committed secrets. Unchanged values then keep their ciphertext.
+	Subpath         string                                     // The path within the target directory which the file copier writes manifests to. Used to find the committed version of a file.
+	EncryptedRegex  string                                     // Keys whose values are encrypted. Defaults to ^(data|stringData)$.
+}
+
+const (
+	sopsVersion           = "3.9.4"
+	sopsDefaultEncrypted  = "^(data|stringData)$"
+	sopsNonceSize         = 32
+	sopsDataKeySize       = 32
+	sopsMetadataKey       = "sops"
+	sopsEncryptedValueFmt = "ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]"
+)
+
+var sopsEncryptedValuePattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+type sopsAgeKey struct {
+	Recipient    string `yaml:"recipient"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// Mirrors the field order of the metadata block written by the sops CLI
+type sopsMetadata struct {
+	KMS            []any        `yaml:"kms"`
+	GCPKMS         []any        `yaml:"gcp_kms"`
+	AzureKV        []any        `yaml:"azure_kv"`
+	HCVault        []any        `yaml:"hc_vault"`
+	Age            []sopsAgeKey `yaml:"age"`
+	LastModified   string       `yaml:"lastmodified"`
+	MAC            string       `yaml:"mac"`
+	PGP            []any        `yaml:"pgp"`
+	EncryptedRegex string       `yaml:"encrypted_regex"`
+	Version        string       `yaml:"version"`
+}
+
+// A decrypted value of a committed file along with its ciphertext
+type sopsValue struct {
+	plaintext  []byte
+	valueType  string
+	ciphertext string
+}
+
+// The committed version of a file, whose data key, metadata and ciphertexts are reused where nothing changed
+type sopsCommittedFile struct {
+	dataKey  []byte
+	metadata sopsMetadata
+	mac      string               // The decrypted MAC
+	values   map[string]sopsValue // Keyed by sopsValueKey
+}
+
+func (s *SOPS) GetTitle() string {
+	return "SOPS encryption"
+}
+
+func (s *SOPS) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	documents, err := yamlUtil.DecodeDocuments(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+
+	objects := make([]*yaml.Node, 0, len(documents))
+	var secrets []*yaml.Node
+	encryptedDocuments := 0
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		obj := doc.Content[0]
+		objects = append(objects, obj)
+		if yamlUtil.GetNode(obj, sopsMetadataKey) != nil {
+			encryptedDocuments++
+			continue
+		}
+		if yamlUtil.IsKubernetesObject(obj) && yamlUtil.GetString(obj, "kind") == "Secret" {
+			secrets = append(secrets, obj)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	if encryptedDocuments > 0 {
+		return errors.New("cannot encrypt a file which mixes SOPS-encrypted and plain text documents")
+	}
+
+	if s.MapRecipientsFn == nil {
+		return errors.New("a recipient mapping function is required")
+	}
+	recipientNames, err := s.MapRecipientsFn(request.Environment)
+	if err != nil {
+		return fmt.Errorf("failed to map environment to age recipients: %w", err)
+	}
+	if len(recipientNames) == 0 {
+		return fmt.Errorf("no age recipients configured for environment %q", request.Environment)
+	}
+	recipients := make([]age.Recipient, 0, len(recipientNames))
+	for _, name := range recipientNames {
+		recipient, err := age.ParseX25519Recipient(name)
+		if err != nil {
+			return fmt.Errorf("failed to parse age recipient %q: %w", name, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	encryptedRegex := s.EncryptedRegex
+	if encryptedRegex == "" {
+		encryptedRegex = sopsDefaultEncrypted
+	}
+	pattern, err := regexp.Compile(encryptedRegex)
+	if err != nil {
+		return fmt.Errorf("failed to compile encrypted regex: %w", err)
+	}
+
+	committed, err := s.readCommittedFile(request, fileContext, pattern, encryptedRegex, sendMsg)
+	if err != nil {
+		return err
+	}
+
+	err = encryptDocuments(objects, pattern, encryptedRegex, recipientNames, recipients, committed)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt file: %w", err)
+	}
+	for _, obj := range secrets {
+		sendMsg(fmt.Sprintf("encrypted %s", yamlUtil.ResourceID(obj)))
+	}
+
+	err = yamlUtil.EncodeDocuments(outputFile, documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return nil
+}
+
+// Reports whether any document of a file carries SOPS metadata
+func hasSOPSMetadata(documents []*yaml.Node) bool {
+	for _, doc := range documents {
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode && yamlUtil.GetNode(doc.Content[0], sopsMetadataKey) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Encrypts the matching values of every document of a file in place and appends the same sops metadata to each, as
+// the sops CLI does. The file has a single data key and its MAC covers the values of all documents. Values of the
+// committed file whose plain text is unchanged keep their ciphertext, and the metadata is kept if nothing changed.
+func encryptDocuments(objects []*yaml.Node, pattern *regexp.Regexp, encryptedRegex string, recipientNames []string, recipients []age.Recipient, committed *sopsCommittedFile) error {
+	var dataKey []byte
+	if committed != nil {
+		dataKey = committed.dataKey
+	} else {
+		dataKey = make([]byte, sopsDataKeySize)
+		_, err := rand.Read(dataKey)
+		if err != nil {
+			return fmt.Errorf("failed to generate data key: %w", err)
+		}
+	}
+
+	hash := sha512.New()
+	for i, obj := range objects {
+		occurrences := make(map[string]int)
+		err := walkSOPSValues(obj, nil, false, pattern, func(node *yaml.Node, path []string, encrypt bool) error {
+			var value any
+			err := node.Decode(&value)
+			if err != nil {
+				return err
+			}
+			if value == nil {
+				return nil
+			}
+
+			plaintext, valueType, err := sopsPlaintext(value)
+			if err != nil {
+				return fmt.Errorf("value at %s: %w", strings.Join(path, "."), err)
+			}
+			hash.Write(plaintext)
+
+			if !encrypt || len(plaintext) == 0 {
+				return nil
+			}
+			var ciphertext string
+			previous, ok := committed.value(sopsValueKey(i, path, occurrences))
+			if ok && previous.valueType == valueType && bytes.Equal(previous.plaintext, plaintext) {
+				ciphertext = previous.ciphertext
+			} else {
+				ciphertext, err = sopsEncrypt(plaintext, valueType, dataKey, strings.Join(path, ":")+":")
+				if err != nil {
+					return err
+				}
+			}
+			node.Kind = yaml.ScalarNode
+			node.Tag = "!!str"
+			node.Style = 0
+			node.Value = ciphertext
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("document %d: %w", i+1, err)
+		}
+	}
+	mac := fmt.Sprintf("%X", hash.Sum(nil))
+
+	var metadata sopsMetadata
+	if committed != nil && committed.mac == mac && slices.Equal(committed.recipients(), recipientNames) {
+		metadata = committed.metadata
+	} else {
+		lastModified := time.Now().UTC().Format(time.RFC3339)
+		encryptedMac, err := sopsEncrypt([]byte(mac), "str", dataKey, lastModified)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt MAC: %w", err)
+		}
+
+		metadata = sopsMetadata{
+			KMS:            []any{},
+			GCPKMS:         []any{},
+			AzureKV:        []any{},
+			HCVault:        []any{},
+			LastModified:   lastModified,
+			MAC:            encryptedMac,
+			PGP:            []any{},
+			EncryptedRegex: encryptedRegex,
+			Version:        sopsVersion,
+		}
+		for i, recipient := range recipients {
+			encryptedKey, ok := committed.encryptedKey(recipientNames[i])
+			if !ok {
+				encryptedKey, err = wrapDataKey(dataKey, recipient)
+				if err != nil {
+					return fmt.Errorf("failed to encrypt data key for %q: %w", recipientNames[i], err)
+				}
+			}
+			metadata.Age = append(metadata.Age, sopsAgeKey{Recipient: recipientNames[i], EncryptedKey: encryptedKey})
+		}
+	}
+
+	for _, obj := range objects {
+		metadataNode := &yaml.Node{}
+		err := metadataNode.Encode(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode sops metadata: %w", err)
+		}
+		obj.Content = append(obj.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sopsMetadataKey},
+			metadataNode,
+		)
+	}
+
+	return nil
+}
+
+// Identifies a value by its document, its key path and its position among values sharing that path, since sequence
+// items share the path of their parent key
+func sopsValueKey(document int, path []string, occurrences map[string]int) string {
+	key := fmt.Sprintf("%d:%s", document, strings.Join(path, ":"))
+	occurrences[key]++
+
+	return fmt.Sprintf("%s#%d", key, occurrences[key])
+}
+
+// Reads and decrypts the committed version of a file, returning nil if there is none, if no identities are
+// configured, or if it was encrypted with a different regex. A committed file which cannot be decrypted is reported and
+// otherwise ignored, so that its values are encrypted afresh.
+func (s *SOPS) readCommittedFile(request *gitops.Request, fileContext *gitops.FileContext, pattern *regexp.Regexp, encryptedRegex string, sendMsg func(string)) (*sopsCommittedFile, error) {
+	if s.MapIdentitiesFn == nil || request.Target == nil || fileContext == nil {
+		return nil, nil
+	}
+
+	identityNames, err := s.MapIdentitiesFn(request.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map environment to age identities: %w", err)
+	}
+	identities := make([]age.Identity, 0, len(identityNames))
+	for _, name := range identityNames {
+		identity, err := age.ParseX25519Identity(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse age identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	path := filepath.Join(request.Paths.RepositoryDir, request.Target.Directory, s.Subpath, filepath.FromSlash(fileContext.Path))
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read committed file: %w", err)
+	}
+
+	committed, err := decryptCommittedFile(content, pattern, encryptedRegex, identities)
+	if err != nil {
+		sendMsg(fmt.Sprintf("encrypting %s afresh as its committed version could not be decrypted: %s", fileContext.Path, err))
+		return nil, nil
+	}
+
+	return committed, nil
+}
+
+func decryptCommittedFile(content []byte, pattern *regexp.Regexp, encryptedRegex string, identities []age.Identity) (*sopsCommittedFile, error) {
+	documents, err := yamlUtil.DecodeDocuments(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+	objects := make([]*yaml.Node, 0, len(documents))
+	for _, doc := range documents {
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+			objects = append(objects, doc.Content[0])
+		}
+	}
+	if len(objects) == 0 || yamlUtil.GetNode(objects[0], sopsMetadataKey) == nil {
+		return nil, nil
+	}
+
+	committed := &sopsCommittedFile{
+		values: make(map[string]sopsValue),
+	}
+	err = yamlUtil.GetNode(objects[0], sopsMetadataKey).Decode(&committed.metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sops metadata: %w", err)
+	}
+	if committed.metadata.EncryptedRegex != encryptedRegex {
+		return nil, nil
+	}
+
+	for _, key := range committed.metadata.Age {
+		committed.dataKey, err = unwrapDataKey(key.EncryptedKey, identities)
+		if err == nil {
+			break
+		}
+	}
+	if committed.dataKey == nil {
+		return nil, errors.New("no identity can decrypt the data key")
+	}
+
+	mac, _, err := sopsDecrypt(committed.metadata.MAC, committed.dataKey, committed.metadata.LastModified)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt MAC: %w", err)
+	}
+	committed.mac = string(mac)
+
+	for i, obj := range objects {
+		occurrences := make(map[string]int)
+		err := walkSOPSValues(obj, nil, false, pattern, func(node *yaml.Node, path []string, encrypt bool) error {
+			if !encrypt || path[0] == sopsMetadataKey || !sopsEncryptedValuePattern.MatchString(node.Value) {
+				return nil
+			}
+			plaintext, valueType, err := sopsDecrypt(node.Value, committed.dataKey, strings.Join(path, ":")+":")
+			if err != nil {
+				return fmt.Errorf("failed to decrypt value at %s: %w", strings.Join(path, "."), err)
+			}
+			committed.values[sopsValueKey(i, path, occurrences)] = sopsValue{
+				plaintext:  plaintext,
+				valueType:  valueType,
+				ciphertext: node.Value,
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return committed, nil
+}
+
+func (c *sopsCommittedFile) value(key string) (sopsValue, bool) {
+	if c == nil {
+		return sopsValue{}, false
+	}
+	value, ok := c.values[key]
+
+	return value, ok
+}
+
+func (c *sopsCommittedFile) recipients() []string {
+	recipients := make([]string, 0, len(c.metadata.Age))
+	for _, key := range c.metadata.Age {
+		recipients = append(recipients, key.Recipient)
+	}
+
+	return recipients
+}
+
+// Returns the data key of the committed file as encrypted for a recipient
+func (c *sopsCommittedFile) encryptedKey(recipient string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	for _, key := range c.metadata.Age {
+		if key.Recipient == recipient {
+			return key.EncryptedKey, true
+		}
+	}
+
+	return "", false
+}
+
+// Visits every scalar leaf in document order, tracking the mapping key path as sops does. Sequence items share the
+// path of their parent key. Comments within encrypted values are dropped since sops would otherwise encrypt them.
+func walkSOPSValues(node *yaml.Node, path []string, encrypt bool, pattern *regexp.Regexp, fn func(node *yaml.Node, path []string, encrypt bool) error) error {
+	if encrypt {
+		node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	}
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		return fmt.Errorf("aliases are not supported in encrypted documents")
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyEncrypt := encrypt || pattern.MatchString(key.Value)
+			if keyEncrypt {
+				key.HeadComment, key.LineComment, key.FootComment = "", "", ""
+			}
+			err := walkSOPSValues(value, append(slices.Clone(path), key.Value), keyEncrypt, pattern, fn)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			err := walkSOPSValues(item, path, encrypt, pattern, fn)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return fn(node, path, encrypt)
+	}
+
+	return nil
+}
+
+// Converts a decoded YAML scalar to the bytes sops hashes and encrypts, along with its sops type name
+func sopsPlaintext(value any) ([]byte, string, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), "str", nil
+	case int:
+		return []byte(strconv.Itoa(v)), "int", nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), "float", nil
+	case bool:
+		if v {
+			return []byte("True"), "bool", nil
+		}
+		return []byte("False"), "bool", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+func sopsEncrypt(plaintext []byte, valueType string, key []byte, additionalData string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, sopsNonceSize)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, sopsNonceSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(nil, iv, plaintext, []byte(additionalData))
+	tagStart := len(out) - aes.BlockSize
+
+	return fmt.Sprintf(sopsEncryptedValueFmt,
+		base64.StdEncoding.EncodeToString(out[:tagStart]),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(out[tagStart:]),
+		valueType,
+	), nil
+}
+
+func sopsDecrypt(value string, key []byte, additionalData string) ([]byte, string, error) {
+	matches := sopsEncryptedValuePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, "", errors.New("value is not in the sops format")
+	}
+	data, err := base64.StdEncoding.DecodeString(matches[1])
+	if err != nil {
+		return nil, "", err
+	}
+	iv, err := base64.StdEncoding.DecodeString(matches[2])
+	if err != nil {
+		return nil, "", err
+	}
+	tag, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return nil, "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return plaintext, matches[4], nil
+}
+
+func wrapDataKey(dataKey []byte, recipient age.Recipient) (string, error) {
+	buf := &bytes.Buffer{}
+	armorWriter := armor.NewWriter(buf)
+	w, err := age.Encrypt(armorWriter, recipient)
+	if err != nil {
+		return "", err
+	}
+	_, err = w.Write(dataKey)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	err = armorWriter.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func unwrapDataKey(encryptedKey string, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(encryptedKey)), identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
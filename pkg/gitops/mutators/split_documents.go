@@ -0,0 +1,219 @@
+package mutators
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/cbroglie/mustache"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This file set mutator splits multi-document YAML files into one file per Kubernetes object
+type SplitDocuments struct {
+	PathTemplate string // A Mustache template for the path of each object relative to the manifests directory. Defaults to {{namespace}}/{{kind}}-{{name}}.yaml.
+
+	once     sync.Once
+	template *mustache.Template
+	parseErr error
+}
+
+const defaultSplitPathTemplate = "{{namespace}}/{{kind}}-{{name}}.yaml"
+
+type splitObject struct {
+	document *yaml.Node
+	path     string
+	source   string
+	claimed  bool
+}
+
+func (s *SplitDocuments) GetTitle() string {
+	return "Split documents"
+}
+
+func (s *SplitDocuments) MutateFiles(ctx context.Context, request *gitops.Request, files gitops.FileSet, sendMsg func(string)) error {
+	s.once.Do(func() {
+		pathTemplate := s.PathTemplate
+		if pathTemplate == "" {
+			pathTemplate = defaultSplitPathTemplate
+		}
+		s.template, s.parseErr = mustache.ParseStringRaw(pathTemplate, true)
+	})
+	if s.parseErr != nil {
+		return fmt.Errorf("failed to parse path template: %w", s.parseErr)
+	}
+
+	sources := make([]string, 0)
+	objects := make([]splitObject, 0)
+	taken := make(map[string]bool)
+
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+
+		fileObjects, err := s.splitFile(files, name)
+		if err != nil {
+			return fmt.Errorf("failed to split %s: %w", name, err)
+		}
+		if fileObjects == nil {
+			taken[name] = true
+			return nil
+		}
+
+		sources = append(sources, name)
+		objects = append(objects, fileObjects...)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Paths are only claimed once all unsplit files are known, so that objects never overwrite them. Objects which are
+	// already alone at their path claim it first, so that splitting previously split files does not move them.
+	for i := range objects {
+		if !taken[objects[i].path] && singleObjectSource(objects, i) {
+			taken[objects[i].path] = true
+			objects[i].claimed = true
+		}
+	}
+	for i := range objects {
+		if !objects[i].claimed {
+			objects[i].path = claimPath(taken, objects[i].path, sendMsg)
+		}
+	}
+
+	for _, source := range sources {
+		err = files.Remove(source)
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w", source, err)
+		}
+	}
+
+	for _, object := range objects {
+		buf := &bytes.Buffer{}
+		err = yamlUtil.EncodeDocuments(buf, []*yaml.Node{object.document})
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", object.path, err)
+		}
+		err = files.WriteFile(object.path, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", object.path, err)
+		}
+	}
+
+	sendMsg(fmt.Sprintf("split %d file(s) into %d object file(s)", len(sources), len(objects)))
+
+	return nil
+}
+
+// Returns the objects of a YAML file with their rendered paths, or nil if the file should be left as it is because it is
+// not YAML, is encrypted with SOPS or contains documents that are not Kubernetes objects
+func (s *SplitDocuments) splitFile(files fs.FS, name string) ([]splitObject, error) {
+	extension := path.Ext(name)
+	if extension != ".yaml" && extension != ".yml" {
+		return nil, nil
+	}
+
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := yamlUtil.DecodeDocuments(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+	// The data key and MAC of a SOPS-encrypted file cover all of its documents, so the split files could not be decrypted
+	if hasSOPSMetadata(documents) {
+		return nil, nil
+	}
+
+	objects := make([]splitObject, 0, len(documents))
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || isNullNode(doc.Content[0]) {
+			continue
+		}
+		obj := doc.Content[0]
+		if !yamlUtil.IsKubernetesObject(obj) {
+			return nil, nil
+		}
+
+		objectPath, err := s.renderPath(obj, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render path for %s: %w", yamlUtil.ResourceID(obj), err)
+		}
+		objects = append(objects, splitObject{document: doc, path: objectPath, source: name})
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	return objects, nil
+}
+
+func (s *SplitDocuments) renderPath(obj *yaml.Node, source string) (string, error) {
+	metadata := yamlUtil.GetNode(obj, "metadata")
+	apiVersion := yamlUtil.GetString(obj, "apiVersion")
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found {
+		group, version = "", apiVersion
+	}
+
+	rendered, err := s.template.Render(map[string]string{
+		"apiVersion": apiVersion,
+		"group":      group,
+		"version":    version,
+		"kind":       strings.ToLower(yamlUtil.GetString(obj, "kind")),
+		"name":       yamlUtil.GetString(metadata, "name"),
+		"namespace":  yamlUtil.GetString(metadata, "namespace"),
+		"file":       strings.TrimSuffix(path.Base(source), path.Ext(source)),
+		"directory":  path.Dir(source),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Empty values, such as the namespace of a cluster-scoped object, collapse into their parent directory
+	objectPath := strings.TrimPrefix(path.Clean("/"+rendered), "/")
+	if !fs.ValidPath(objectPath) || objectPath == "." || path.Base(objectPath) == path.Ext(objectPath) {
+		return "", fmt.Errorf("%q is not a valid file path", rendered)
+	}
+
+	return objectPath, nil
+}
+
+// Returns whether the object at index i is the only object of its source file and was rendered to that same path
+func singleObjectSource(objects []splitObject, i int) bool {
+	if objects[i].path != objects[i].source {
+		return false
+	}
+	for j, object := range objects {
+		if j != i && object.source == objects[i].source {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Claims a path for an object, appending a numeric suffix if it is already taken
+func claimPath(taken map[string]bool, objectPath string, sendMsg func(string)) string {
+	claimed := objectPath
+	extension := path.Ext(objectPath)
+	for i := 2; taken[claimed]; i++ {
+		claimed = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(objectPath, extension), i, extension)
+	}
+	if claimed != objectPath {
+		sendMsg(fmt.Sprintf("%s is already taken, writing to %s instead", objectPath, claimed))
+	}
+	taken[claimed] = true
+
+	return claimed
+}
@@ -0,0 +1,124 @@
+package mutators
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This mutator adds or overrides labels and annotations on every Kubernetes object and pod template.
+// Values are Go templates evaluated against the request, e.g. "{{ .Source.Metadata.CommitSHA }}" or `{{ index .Metadata "team" }}`.
+// Values which render empty are not set.
+type StandardLabels struct {
+	Labels      map[string]string
+	Annotations map[string]string
+
+	once        sync.Once
+	labels      map[string]*template.Template
+	annotations map[string]*template.Template
+	parseErr    error
+}
+
+var invalidLabelValueCharacters = regexp.MustCompile(`[^A-Za-z0-9_.\-]`)
+
+func (s *StandardLabels) GetTitle() string {
+	return "Standard labels and annotations"
+}
+
+func (s *StandardLabels) MutateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	s.once.Do(s.parse)
+	if s.parseErr != nil {
+		return s.parseErr
+	}
+
+	labels, err := renderAll(s.labels, request)
+	if err != nil {
+		return fmt.Errorf("failed to render labels: %w", err)
+	}
+	for key, value := range labels {
+		labels[key] = sanitiseLabelValue(value)
+	}
+
+	annotations, err := renderAll(s.annotations, request)
+	if err != nil {
+		return fmt.Errorf("failed to render annotations: %w", err)
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse file as YAML: %w", err)
+	}
+
+	mutated := false
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || !yamlUtil.IsKubernetesObject(doc.Content[0]) {
+			continue
+		}
+		obj := doc.Content[0]
+
+		targets := append([]*yaml.Node{obj}, yamlUtil.PodTemplates(obj)...)
+		for _, target := range targets {
+			metadata := yamlUtil.GetOrCreateMap(target, "metadata")
+			setAll(metadata, "labels", labels)
+			setAll(metadata, "annotations", annotations)
+		}
+		mutated = true
+	}
+
+	if !mutated {
+		return nil
+	}
+
+	err = yamlUtil.EncodeDocuments(outputFile, documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return nil
+}
+
+func (s *StandardLabels) parse() {
+	s.labels, s.parseErr = parseAll("label", s.Labels)
+	if s.parseErr != nil {
+		return
+	}
+	s.annotations, s.parseErr = parseAll("annotation", s.Annotations)
+}
+
+// Sets each value under the named mapping of metadata in a deterministic order
+func setAll(metadata *yaml.Node, field string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+
+	mapping := yamlUtil.GetOrCreateMap(metadata, field)
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		yamlUtil.SetMappingValue(mapping, key, values[key])
+	}
+}
+
+// Coerces a value into a valid label value of at most 63 alphanumeric characters, '-', '_' or '.', beginning and ending with an alphanumeric character
+func sanitiseLabelValue(value string) string {
+	value = invalidLabelValueCharacters.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+
+	return strings.Trim(value, "-_.")
+}
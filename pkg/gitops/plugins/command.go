@@ -0,0 +1,246 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// Describes an external executable run by the plugin adapters
+type Command struct {
+	Path       string        // The executable to run. A name without a slash is looked up in PATH.
+	Args       []string      // Arguments passed to the executable
+	Env        []string      // Additional environment variables in KEY=value form
+	InheritEnv bool          // Whether to pass the environment of the server to the executable. By default only PATH is passed.
+	Timeout    time.Duration // The maximum run time of a single invocation. Defaults to one minute.
+	Header     bool          // Whether to write the request context as a single line of JSON on stdin before the file
+}
+
+const (
+	defaultTimeout  = time.Minute
+	envPrefix       = "GITOPS_"
+	maxStderrReport = 4096
+)
+
+// The request context passed to executables as JSON
+type RequestContext struct {
+	Environment      string         `json:"environment"`
+	AppName          string         `json:"appName"`
+	UpdateIdentifier string         `json:"updateIdentifier"`
+	DryRun           bool           `json:"dryRun"`
+	Metadata         map[string]any `json:"metadata"`
+	Source           SourceContext  `json:"source"`
+	File             *FileContext   `json:"file,omitempty"`
+}
+
+type SourceContext struct {
+	Repository string         `json:"repository"`
+	CommitSHA  string         `json:"commitSha"`
+	Actor      string         `json:"actor"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+type FileContext struct {
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+}
+
+func newRequestContext(request *gitops.Request, fileContext *gitops.FileContext) *RequestContext {
+	requestContext := &RequestContext{
+		Environment:      request.Environment,
+		AppName:          request.AppName,
+		UpdateIdentifier: request.UpdateIdentifier,
+		DryRun:           request.DryRun,
+		Metadata:         request.Metadata,
+	}
+	if request.Source != nil {
+		if request.Source.Repository != nil {
+			requestContext.Source.Repository = request.Source.Repository.URL
+		}
+		if request.Source.Metadata != nil {
+			requestContext.Source.CommitSHA = request.Source.Metadata.CommitSHA
+			requestContext.Source.Actor = request.Source.Metadata.Actor
+			requestContext.Source.Attributes = request.Source.Metadata.Attributes
+		}
+	}
+	if fileContext != nil {
+		requestContext.File = &FileContext{
+			Path:        fileContext.Path,
+			Size:        fileContext.Size,
+			ContentType: fileContext.ContentType,
+		}
+	}
+
+	return requestContext
+}
+
+// Returns the request context as environment variables, with the full context as JSON in GITOPS_REQUEST
+func (r *RequestContext) environ() ([]string, error) {
+	requestJSON, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request context: %w", err)
+	}
+
+	env := []string{
+		envPrefix + "ENVIRONMENT=" + r.Environment,
+		envPrefix + "APP_NAME=" + r.AppName,
+		envPrefix + "UPDATE_IDENTIFIER=" + r.UpdateIdentifier,
+		envPrefix + "DRY_RUN=" + strconv.FormatBool(r.DryRun),
+		envPrefix + "SOURCE_REPOSITORY=" + r.Source.Repository,
+		envPrefix + "SOURCE_COMMIT_SHA=" + r.Source.CommitSHA,
+		envPrefix + "SOURCE_ACTOR=" + r.Source.Actor,
+		envPrefix + "REQUEST=" + string(requestJSON),
+	}
+	if r.File != nil {
+		env = append(env,
+			envPrefix+"FILE_PATH="+r.File.Path,
+			envPrefix+"FILE_SIZE="+strconv.FormatInt(r.File.Size, 10),
+			envPrefix+"FILE_CONTENT_TYPE="+r.File.ContentType,
+		)
+	}
+
+	return env, nil
+}
+
+type invocation struct {
+	title          string
+	requestContext *RequestContext
+	stdin          []byte
+	sandbox        string // Working directory of the process, created under the request's temporary directory
+	extraEnv       []string
+}
+
+// Returns stdin, optionally preceded by the request context as a single line of JSON
+func (inv *invocation) stdinWithHeader(header bool) (*bytes.Buffer, error) {
+	stdin := &bytes.Buffer{}
+	if header {
+		err := json.NewEncoder(stdin).Encode(inv.requestContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request header: %w", err)
+		}
+	}
+	stdin.Write(inv.stdin)
+
+	return stdin, nil
+}
+
+// Runs the command in its sandbox, forwarding stderr to sendMsg line by line, and returns stdout
+func (c *Command) run(ctx context.Context, inv *invocation, sendMsg func(string)) ([]byte, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	requestEnv, err := inv.requestContext.environ()
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := inv.stdinWithHeader(c.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Dir = inv.sandbox
+	cmd.Env = c.environ(inv.sandbox, requestEnv, inv.extraEnv)
+	cmd.Stdin = stdin
+	cmd.WaitDelay = time.Second
+
+	stdout := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	stderr := &stderrForwarder{prefix: inv.title, sendMsg: sendMsg}
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+	stderr.flush()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%s timed out after %s", c.Path, timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.tail.Len() > 0 {
+			return nil, fmt.Errorf("%s exited with code %d: %s", c.Path, exitErr.ExitCode(), strings.TrimSpace(stderr.tail.String()))
+		}
+		return nil, fmt.Errorf("failed to run %s: %w", c.Path, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (c *Command) environ(sandbox string, requestEnv, extraEnv []string) []string {
+	env := make([]string, 0)
+	if c.InheritEnv {
+		env = append(env, os.Environ()...)
+	} else {
+		env = append(env, "PATH="+os.Getenv("PATH"))
+	}
+	env = append(env, "HOME="+sandbox, "TMPDIR="+sandbox)
+	env = append(env, requestEnv...)
+	env = append(env, extraEnv...)
+
+	return append(env, c.Env...)
+}
+
+// Creates a sandbox directory under the request's temporary directory, returning a function to remove it
+func newSandbox(request *gitops.Request) (string, func(), error) {
+	dir, err := os.MkdirTemp(request.Paths.TempDir, "plugin-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create plugin sandbox: %w", err)
+	}
+
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// Forwards complete lines written to it to sendMsg, keeping the most recent output for error messages
+type stderrForwarder struct {
+	prefix  string
+	sendMsg func(string)
+	partial bytes.Buffer
+	tail    bytes.Buffer
+}
+
+func (s *stderrForwarder) Write(p []byte) (int, error) {
+	s.tail.Write(p)
+	if s.tail.Len() > maxStderrReport {
+		s.tail.Next(s.tail.Len() - maxStderrReport)
+	}
+
+	s.partial.Write(p)
+	for {
+		i := bytes.IndexByte(s.partial.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := s.partial.Next(i + 1)
+		s.sendLine(string(line[:i]))
+	}
+
+	return len(p), nil
+}
+
+func (s *stderrForwarder) flush() {
+	if s.partial.Len() > 0 {
+		s.sendLine(s.partial.String())
+		s.partial.Reset()
+	}
+}
+
+func (s *stderrForwarder) sendLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line != "" {
+		s.sendMsg(fmt.Sprintf("%s: %s", s.prefix, line))
+	}
+}
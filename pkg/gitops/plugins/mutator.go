@@ -0,0 +1,167 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// This mutator runs an external executable for every file. The file is written to stdin and the mutated file is read
+// from stdout. Writing nothing to stdout leaves the file unchanged.
+type Mutator struct {
+	Title   string // The title reported for the mutator. Defaults to the command path.
+	Command Command
+}
+
+func (m *Mutator) GetTitle() string {
+	return title(m.Title, m.Command.Path)
+}
+
+func (m *Mutator) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	input, err := io.ReadAll(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	sandbox, cleanup, err := newSandbox(request)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	output, err := m.Command.run(ctx, &invocation{
+		title:          m.GetTitle(),
+		requestContext: newRequestContext(request, fileContext),
+		stdin:          input,
+		sandbox:        sandbox,
+	}, sendMsg)
+	if err != nil {
+		return err
+	}
+
+	_, err = outputFile.Write(output)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+// This file set mutator runs an external executable once for all manifests. The manifests are copied into a directory
+// named in GITOPS_MANIFESTS_DIR, and any files the executable adds, changes or removes there are applied to the
+// manifests. Lines written to stdout are reported as progress.
+type FileSetMutator struct {
+	Title   string // The title reported for the mutator. Defaults to the command path.
+	Command Command
+}
+
+func (m *FileSetMutator) GetTitle() string {
+	return title(m.Title, m.Command.Path)
+}
+
+func (m *FileSetMutator) MutateFiles(ctx context.Context, request *gitops.Request, files gitops.FileSet, sendMsg func(string)) error {
+	sandbox, cleanup, err := newSandbox(request)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	manifestsDir := filepath.Join(sandbox, "manifests")
+	original, err := copyManifests(files, manifestsDir)
+	if err != nil {
+		return err
+	}
+
+	output, err := m.Command.run(ctx, &invocation{
+		title:          m.GetTitle(),
+		requestContext: newRequestContext(request, nil),
+		sandbox:        sandbox,
+		extraEnv:       []string{envPrefix + "MANIFESTS_DIR=" + manifestsDir},
+	}, sendMsg)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			sendMsg(fmt.Sprintf("%s: %s", m.GetTitle(), line))
+		}
+	}
+
+	mutated := os.DirFS(manifestsDir)
+	err = fs.WalkDir(mutated, ".", func(name string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || !d.Type().IsRegular() {
+			return walkErr
+		}
+
+		content, err := fs.ReadFile(mutated, name)
+		if err != nil {
+			return err
+		}
+		originalContent, existed := original[name]
+		delete(original, name)
+		if existed && bytes.Equal(content, originalContent) {
+			return nil
+		}
+
+		return files.WriteFile(name, content)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply mutated manifests: %w", err)
+	}
+
+	for name := range original {
+		err = files.Remove(name)
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Copies every file of a file system into a directory, returning the copied contents by path
+func copyManifests(files fs.FS, dir string) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil || d.IsDir() {
+			return walkErr
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(fullPath), 0755)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(fullPath, content, 0644)
+		if err != nil {
+			return err
+		}
+		contents[name] = content
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy manifests into the plugin sandbox: %w", err)
+	}
+
+	return contents, nil
+}
+
+func title(configured, fallback string) string {
+	if configured != "" {
+		return configured
+	}
+
+	return fallback
+}
@@ -0,0 +1,135 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// The validation result an executable writes to stdout as JSON
+type ValidationOutput struct {
+	Valid    *bool           `json:"valid"`    // Defaults to whether there are no errors
+	Errors   []string        `json:"errors"`   // Errors which make the file invalid
+	Findings []FindingOutput `json:"findings"` // Findings with a severity of error, warning or info
+}
+
+type FindingOutput struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Path     string `json:"path"` // Defaults to the file being validated
+}
+
+// This validator runs an external executable for every file. The file is written to stdin and a ValidationOutput is
+// read from stdout.
+type Validator struct {
+	Title   string // The title reported for the validator. Defaults to the command path.
+	Command Command
+}
+
+func (v *Validator) GetTitle() string {
+	return title(v.Title, v.Command.Path)
+}
+
+func (v *Validator) ValidateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	input, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	sandbox, cleanup, err := newSandbox(request)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	output, err := v.Command.run(ctx, &invocation{
+		title:          v.GetTitle(),
+		requestContext: newRequestContext(request, fileContext),
+		stdin:          input,
+		sandbox:        sandbox,
+	}, sendMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValidationOutput(output)
+}
+
+// This set validator runs an external executable once for all manifests. The manifests are copied into a directory
+// named in GITOPS_MANIFESTS_DIR and a ValidationOutput is read from stdout.
+type SetValidator struct {
+	Title   string // The title reported for the validator. Defaults to the command path.
+	Command Command
+}
+
+func (v *SetValidator) GetTitle() string {
+	return title(v.Title, v.Command.Path)
+}
+
+func (v *SetValidator) ValidateFiles(ctx context.Context, request *gitops.Request, files fs.FS, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	sandbox, cleanup, err := newSandbox(request)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	manifestsDir := filepath.Join(sandbox, "manifests")
+	_, err = copyManifests(files, manifestsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := v.Command.run(ctx, &invocation{
+		title:          v.GetTitle(),
+		requestContext: newRequestContext(request, nil),
+		sandbox:        sandbox,
+		extraEnv:       []string{envPrefix + "MANIFESTS_DIR=" + manifestsDir},
+	}, sendMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValidationOutput(output)
+}
+
+func parseValidationOutput(output []byte) (*gitops.ValidationResult, error) {
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil, fmt.Errorf("no validation result was written to stdout")
+	}
+
+	validationOutput := &ValidationOutput{}
+	err := json.Unmarshal(output, validationOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse validation result: %w", err)
+	}
+
+	result := &gitops.ValidationResult{
+		IsValid: len(validationOutput.Errors) == 0,
+	}
+	if validationOutput.Valid != nil {
+		result.IsValid = *validationOutput.Valid
+	}
+	for _, message := range validationOutput.Errors {
+		result.Errors = append(result.Errors, errors.New(message))
+	}
+	for _, finding := range validationOutput.Findings {
+		severity, err := gitops.ParseSeverity(finding.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid finding: %w", err)
+		}
+		result.Findings = append(result.Findings, gitops.Finding{
+			Severity: severity,
+			Message:  finding.Message,
+			Path:     finding.Path,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,252 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// A WebAssembly module compiled for WASI preview 1 (wasip1) run by the WASM plugin adapters. Modules follow the same
+// protocol as external processes, but are isolated from the server: they have no access to the network, the
+// environment of the server or any files other than the manifests given to set validators.
+type Module struct {
+	Path           string        // The path of the .wasm file
+	MemoryLimitMiB uint32        // The maximum memory of a single invocation. Defaults to 128 MiB.
+	Timeout        time.Duration // The maximum run time of a single invocation. Defaults to ten seconds.
+	Env            []string      // Additional environment variables in KEY=value form
+	Header         bool          // Whether to write the request context as a single line of JSON on stdin before the file
+
+	once       sync.Once
+	runtime    wazero.Runtime
+	compiled   wazero.CompiledModule
+	compileErr error
+}
+
+const (
+	abiVersion             = "1"
+	defaultModuleTimeout   = 10 * time.Second
+	defaultModuleMemoryMiB = 128
+	wasmPagesPerMiB        = 16 // WebAssembly memory pages are 64 KiB
+	moduleManifestsDir     = "/manifests"
+)
+
+// Compiles the module. Calling this when configuring the server surfaces invalid modules before the first request,
+// otherwise the module is compiled on first use.
+func (m *Module) Compile(ctx context.Context) error {
+	m.once.Do(func() {
+		memoryLimit := m.MemoryLimitMiB
+		if memoryLimit == 0 {
+			memoryLimit = defaultModuleMemoryMiB
+		}
+
+		m.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+			WithMemoryLimitPages(memoryLimit*wasmPagesPerMiB).
+			WithCloseOnContextDone(true))
+
+		_, err := wasi_snapshot_preview1.Instantiate(ctx, m.runtime)
+		if err != nil {
+			m.compileErr = fmt.Errorf("failed to instantiate WASI: %w", err)
+			return
+		}
+
+		binary, err := os.ReadFile(m.Path)
+		if err != nil {
+			m.compileErr = fmt.Errorf("failed to read module: %w", err)
+			return
+		}
+		m.compiled, err = m.runtime.CompileModule(ctx, binary)
+		if err != nil {
+			m.compileErr = fmt.Errorf("failed to compile module %s: %w", m.Path, err)
+		}
+	})
+
+	return m.compileErr
+}
+
+// Releases the compiled module and its runtime
+func (m *Module) Close(ctx context.Context) error {
+	if m.runtime == nil {
+		return nil
+	}
+
+	return m.runtime.Close(ctx)
+}
+
+// Instantiates and runs the module once, forwarding stderr to sendMsg line by line, and returns stdout. The
+// manifests directory is mounted read-only at /manifests if given.
+func (m *Module) run(ctx context.Context, inv *invocation, manifestsDir string, sendMsg func(string)) ([]byte, error) {
+	err := m.Compile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := m.Timeout
+	if timeout == 0 {
+		timeout = defaultModuleTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	requestEnv, err := inv.requestContext.environ()
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := inv.stdinWithHeader(m.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &stderrForwarder{prefix: inv.title, sendMsg: sendMsg}
+
+	// Modules are instantiated without a name so that several can run at once
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(filepath.Base(m.Path)).
+		WithStdin(stdin).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+
+	env := append([]string{envPrefix + "ABI_VERSION=" + abiVersion}, requestEnv...)
+	env = append(env, inv.extraEnv...)
+	for _, variable := range append(env, m.Env...) {
+		key, value, _ := strings.Cut(variable, "=")
+		config = config.WithEnv(key, value)
+	}
+	if manifestsDir != "" {
+		config = config.WithFSConfig(wazero.NewFSConfig().WithReadOnlyDirMount(manifestsDir, moduleManifestsDir))
+	}
+
+	module, err := m.runtime.InstantiateModule(ctx, m.compiled, config)
+	if module != nil {
+		module.Close(ctx)
+	}
+	stderr.flush()
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 0:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded:
+		return nil, fmt.Errorf("%s timed out after %s", m.Path, timeout)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() != sys.ExitCodeContextCanceled && stderr.tail.Len() > 0:
+		return nil, fmt.Errorf("%s exited with code %d: %s", m.Path, exitErr.ExitCode(), strings.TrimSpace(stderr.tail.String()))
+	default:
+		return nil, fmt.Errorf("failed to run %s: %w", m.Path, err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// This mutator runs a WebAssembly module for every file, following the protocol of Mutator
+type WASMMutator struct {
+	Title  string // The title reported for the mutator. Defaults to the module path.
+	Module *Module
+}
+
+func (m *WASMMutator) GetTitle() string {
+	return title(m.Title, m.Module.Path)
+}
+
+func (m *WASMMutator) MutateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, inputFile io.Reader, outputFile io.Writer, sendMsg func(string)) error {
+	input, err := io.ReadAll(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	output, err := m.Module.run(ctx, &invocation{
+		title:          m.GetTitle(),
+		requestContext: newRequestContext(request, fileContext),
+		stdin:          input,
+	}, "", sendMsg)
+	if err != nil {
+		return err
+	}
+
+	_, err = outputFile.Write(output)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+// This validator runs a WebAssembly module for every file, following the protocol of Validator
+type WASMValidator struct {
+	Title  string // The title reported for the validator. Defaults to the module path.
+	Module *Module
+}
+
+func (v *WASMValidator) GetTitle() string {
+	return title(v.Title, v.Module.Path)
+}
+
+func (v *WASMValidator) ValidateFile(ctx context.Context, request *gitops.Request, fileContext *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	input, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	output, err := v.Module.run(ctx, &invocation{
+		title:          v.GetTitle(),
+		requestContext: newRequestContext(request, fileContext),
+		stdin:          input,
+	}, "", sendMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValidationOutput(output)
+}
+
+// This set validator runs a WebAssembly module once for all manifests, following the protocol of SetValidator. The
+// manifests are mounted read-only at /manifests.
+type WASMSetValidator struct {
+	Title  string // The title reported for the validator. Defaults to the module path.
+	Module *Module
+}
+
+func (v *WASMSetValidator) GetTitle() string {
+	return title(v.Title, v.Module.Path)
+}
+
+func (v *WASMSetValidator) ValidateFiles(ctx context.Context, request *gitops.Request, files fs.FS, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	sandbox, cleanup, err := newSandbox(request)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	_, err = copyManifests(files, sandbox)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := v.Module.run(ctx, &invocation{
+		title:          v.GetTitle(),
+		requestContext: newRequestContext(request, nil),
+		extraEnv:       []string{envPrefix + "MANIFESTS_DIR=" + moduleManifestsDir},
+	}, sandbox, sendMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseValidationOutput(output)
+}
@@ -1,14 +1,279 @@
 package gitops
 
-import "context"
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+)
 
 type CreateReviewResult struct {
-	Created   bool
-	URL       string
-	Completed bool
+	Created    bool
+	URL        string
+	Completed  bool
+	Assignment *ReviewAssignment // What was assigned to the review when it was created or updated, if anything
+}
+
+// The labels, assignees, reviewers and milestone of a review
+type ReviewAssignment struct {
+	Labels        []string
+	Assignees     []string
+	Reviewers     []string
+	TeamReviewers []string // Teams given as org/team
+	Milestone     string
+}
+
+// Returns a summary such as "labels: env:production; reviewers: alice, org/ops"
+func (a *ReviewAssignment) String() string {
+	parts := make([]string, 0)
+	for _, field := range []struct {
+		name   string
+		values []string
+	}{
+		{"labels", a.Labels},
+		{"assignees", a.Assignees},
+		{"reviewers", append(slices.Clone(a.Reviewers), a.TeamReviewers...)},
+	} {
+		if len(field.values) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %s", field.name, strings.Join(field.values, ", ")))
+		}
+	}
+	if a.Milestone != "" {
+		parts = append(parts, "milestone: "+a.Milestone)
+	}
+
+	return strings.Join(parts, "; ")
 }
 
 type Reviewer interface {
 	CreateReview(ctx context.Context, req *Request, target *Target, sendMsg func(string)) (*CreateReviewResult, error)
 	CompleteReview(ctx context.Context, req *Request, createReviewResult *CreateReviewResult, sendMsg func(string)) (bool, error)
 }
+
+// Implemented by reviewers which can prune the open reviews and branches of a configuration repository
+type ReviewPruner interface {
+	ListOpenReviews(ctx context.Context, repository Repository) ([]*OpenReview, error)
+	CloseReview(ctx context.Context, review *OpenReview, comment string) error
+	ListBranches(ctx context.Context, repository Repository) ([]*RepositoryBranch, error)
+	RemoveBranch(ctx context.Context, repository Repository, branch string) error
+}
+
+type OpenReview struct {
+	URL          string
+	SourceBranch string
+	TargetBranch string
+	UpdatedAt    time.Time
+}
+
+type RepositoryBranch struct {
+	Name      string
+	UpdatedAt time.Time // The time of the latest commit
+}
+
+// The rendered title and description of a review, and a comment summarising the changes for when an existing review is
+// updated
+type ReviewContent struct {
+	Title         string
+	Description   string
+	UpdateComment string
+}
+
+// Summarises the changes of the next branch compared to the trunk branch
+type DiffStats struct {
+	Files     []FileDiffStats
+	Additions int
+	Deletions int
+}
+
+type FileDiffStats struct {
+	Path      string
+	Status    string // One of added, modified or deleted
+	Additions int
+	Deletions int
+}
+
+// Returns a summary such as "3 files changed, 10 insertions(+), 2 deletions(-)"
+func (d *DiffStats) String() string {
+	files := "files"
+	if len(d.Files) == 1 {
+		files = "file"
+	}
+
+	return fmt.Sprintf("%d %s changed, %d insertions(+), %d deletions(-)", len(d.Files), files, d.Additions, d.Deletions)
+}
+
+// The data available to review templates
+type ReviewTemplateData struct {
+	Request    *Request
+	Target     *Target
+	Source     ReviewSource
+	Diff       *DiffStats // Nil if the changes could not be compared
+	CommitDiff *DiffStats // The changes since the last push. Nil if they could not be compared.
+	Findings   []Finding  // Non-blocking validation findings
+	Warnings   []Finding  // The findings with a severity of warning
+}
+
+type ReviewSource struct {
+	Repository string
+	CommitSHA  string
+	Actor      string
+	Attributes map[string]any
+}
+
+func NewReviewTemplateData(req *Request, target *Target) *ReviewTemplateData {
+	data := &ReviewTemplateData{
+		Request:    req,
+		Target:     target,
+		Diff:       req.Diff,
+		CommitDiff: req.CommitDiff,
+		Findings:   req.Findings,
+		Warnings:   make([]Finding, 0),
+	}
+	if req.Source != nil {
+		if req.Source.Repository != nil {
+			data.Source.Repository = req.Source.Repository.URL
+		}
+		if req.Source.Metadata != nil {
+			data.Source.CommitSHA = req.Source.Metadata.CommitSHA
+			data.Source.Actor = req.Source.Metadata.Actor
+			data.Source.Attributes = req.Source.Metadata.Attributes
+		}
+	}
+	for _, finding := range req.Findings {
+		if finding.Severity == SeverityWarning {
+			data.Warnings = append(data.Warnings, finding)
+		}
+	}
+
+	return data
+}
+
+const DefaultReviewTitle = `Promote {{ .Request.AppName }} [{{ .Request.UpdateIdentifier }}] to {{ .Request.Environment }}`
+
+const DefaultReviewDescription = `- **Target Environment:** {{ .Request.Environment }}
+- **App Name:** {{ .Request.AppName }}
+{{- with .Source.Repository }}
+- **Source Repository:** [{{ . }}]({{ . }})
+- **Source Branch:** [{{ $.Request.UpdateIdentifier }}]({{ . }}/tree/{{ $.Request.UpdateIdentifier }})
+{{- end }}
+{{- with .Source.CommitSHA }}
+- **Source Commit:** ` + "`{{ . }}`" + `
+{{- end }}
+{{- with .Source.Actor }}
+- **Actor:** {{ . }}
+{{- end }}
+{{- with .Diff }}
+
+**Changes:** {{ . }}
+{{- end }}
+{{- with .Findings }}
+
+#### Validation Findings
+
+{{ range . }}- **{{ .Severity }}** ` + "`{{ .Path }}`" + `: {{ .Message }} _({{ .Validator }})_
+{{ end }}
+{{- end }}`
+
+const DefaultReviewUpdateComment = `Updated with {{ .Request.AppName }} [{{ .Request.UpdateIdentifier }}]
+{{- with .Source.CommitSHA }} from ` + "`{{ . }}`" + `{{ end }}
+{{- with .Source.Actor }} by {{ . }}{{ end }}.
+{{- with .CommitDiff }}
+
+**Changes since the last push:** {{ . }}
+
+{{ range .Files }}- {{ .Status }} ` + "`{{ .Path }}`" + ` (+{{ .Additions }} -{{ .Deletions }})
+{{ end }}
+{{- end }}`
+
+// Go templates for the title and description of reviews and the comment on an updated review, rendered with
+// ReviewTemplateData. The sprig function library is available. Descriptions and comments should be Markdown, which all
+// reviewers render.
+type ReviewTemplate struct {
+	Title         string           // Defaults to DefaultReviewTitle. Rendered onto a single line.
+	Description   string           // Defaults to DefaultReviewDescription
+	UpdateComment string           // Defaults to DefaultReviewUpdateComment
+	Funcs         template.FuncMap // Additional template functions. These take precedence over the built-in functions.
+
+	once          sync.Once
+	title         *template.Template
+	description   *template.Template
+	updateComment *template.Template
+	parseErr      error
+}
+
+func (t *ReviewTemplate) parse() error {
+	t.once.Do(func() {
+		funcs := sprig.TxtFuncMap()
+		for _, name := range []string{"env", "expandenv"} {
+			delete(funcs, name) // Templates must not read the environment of the server
+		}
+		for name, fn := range t.Funcs {
+			funcs[name] = fn
+		}
+
+		title := t.Title
+		if title == "" {
+			title = DefaultReviewTitle
+		}
+		description := t.Description
+		if description == "" {
+			description = DefaultReviewDescription
+		}
+		updateComment := t.UpdateComment
+		if updateComment == "" {
+			updateComment = DefaultReviewUpdateComment
+		}
+
+		t.title, t.parseErr = template.New("title").Funcs(funcs).Parse(title)
+		if t.parseErr != nil {
+			t.parseErr = fmt.Errorf("failed to parse review title template: %w", t.parseErr)
+			return
+		}
+		t.description, t.parseErr = template.New("description").Funcs(funcs).Parse(description)
+		if t.parseErr != nil {
+			t.parseErr = fmt.Errorf("failed to parse review description template: %w", t.parseErr)
+			return
+		}
+		t.updateComment, t.parseErr = template.New("updateComment").Funcs(funcs).Parse(updateComment)
+		if t.parseErr != nil {
+			t.parseErr = fmt.Errorf("failed to parse review update comment template: %w", t.parseErr)
+		}
+	})
+
+	return t.parseErr
+}
+
+func (t *ReviewTemplate) Render(data *ReviewTemplateData) (*ReviewContent, error) {
+	err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	title := &bytes.Buffer{}
+	err = t.title.Execute(title, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render review title: %w", err)
+	}
+	description := &bytes.Buffer{}
+	err = t.description.Execute(description, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render review description: %w", err)
+	}
+	updateComment := &bytes.Buffer{}
+	err = t.updateComment.Execute(updateComment, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render review update comment: %w", err)
+	}
+
+	return &ReviewContent{
+		Title:         strings.Join(strings.Fields(title.String()), " "),
+		Description:   strings.TrimSpace(description.String()),
+		UpdateComment: strings.TrimSpace(updateComment.String()),
+	}, nil
+}
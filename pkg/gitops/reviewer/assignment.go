@@ -0,0 +1,172 @@
+package reviewer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// Determines the labels, assignees, reviewers and milestone of newly created reviews. Users are given by username and
+// teams as org/team, with or without a leading @.
+type Assignment struct {
+	Labels         []string                           // Labels of every review
+	LabelsFn       func(req *gitops.Request) []string // Additional labels of a review, such as EnvironmentLabels
+	Assignees      []string                           // Users assigned to every review
+	Reviewers      map[string][]string                // Users and teams requested to review by environment. The key "*" applies to every environment.
+	CodeOwnersFile string                             // A CODEOWNERS-style file in the configuration repository. The owners of the changed files are requested to review.
+	Milestone      string                             // The title of an existing milestone
+}
+
+// Labels a review with the environment and app name of the request, such as env:production and app:foo
+func EnvironmentLabels(req *gitops.Request) []string {
+	return []string{"env:" + req.Environment, "app:" + req.AppName}
+}
+
+// Resolves the assignment of a review for a request, returning nil if there is nothing to assign
+func (a *Assignment) resolve(req *gitops.Request, target *gitops.Target) (*gitops.ReviewAssignment, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	assignment := &gitops.ReviewAssignment{
+		Labels:        slices.Clone(a.Labels),
+		Assignees:     make([]string, 0, len(a.Assignees)),
+		Reviewers:     make([]string, 0),
+		TeamReviewers: make([]string, 0),
+		Milestone:     a.Milestone,
+	}
+	if a.LabelsFn != nil {
+		assignment.Labels = append(assignment.Labels, a.LabelsFn(req)...)
+	}
+	assignment.Labels = uniqueNonEmpty(assignment.Labels)
+
+	for _, assignee := range a.Assignees {
+		assignment.Assignees = append(assignment.Assignees, strings.TrimPrefix(assignee, "@"))
+	}
+	assignment.Assignees = uniqueNonEmpty(assignment.Assignees)
+
+	reviewers := slices.Concat(a.Reviewers["*"], a.Reviewers[req.Environment])
+	if a.CodeOwnersFile != "" {
+		owners, err := codeOwners(filepath.Join(req.Paths.RepositoryDir, filepath.Clean("/"+a.CodeOwnersFile)), changedPaths(req, target))
+		if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, owners...)
+	}
+	for _, reviewer := range reviewers {
+		reviewer = strings.TrimPrefix(reviewer, "@")
+		if strings.Contains(reviewer, "/") {
+			assignment.TeamReviewers = append(assignment.TeamReviewers, reviewer)
+		} else {
+			assignment.Reviewers = append(assignment.Reviewers, reviewer)
+		}
+	}
+	assignment.Reviewers = uniqueNonEmpty(assignment.Reviewers)
+	assignment.TeamReviewers = uniqueNonEmpty(assignment.TeamReviewers)
+
+	if len(assignment.Labels) == 0 && len(assignment.Assignees) == 0 && len(assignment.Reviewers) == 0 &&
+		len(assignment.TeamReviewers) == 0 && assignment.Milestone == "" {
+		return nil, nil
+	}
+
+	return assignment, nil
+}
+
+// Returns the paths changed by a request, or the target directory if the changes could not be compared
+func changedPaths(req *gitops.Request, target *gitops.Target) []string {
+	if req.Diff == nil {
+		return []string{strings.Trim(target.Directory, "/") + "/"}
+	}
+
+	paths := make([]string, 0, len(req.Diff.Files))
+	for _, file := range req.Diff.Files {
+		paths = append(paths, file.Path)
+	}
+
+	return paths
+}
+
+type codeOwnersRule struct {
+	pattern gitignore.Pattern
+	owners  []string
+}
+
+// Returns the owners of the given paths from a CODEOWNERS-style file, where the last matching rule of a path wins. Email
+// owners are ignored. A missing file has no owners.
+func codeOwners(file string, paths []string) ([]string, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Debug("code owners file does not exist", "file", file)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code owners file: %w", err)
+	}
+
+	rules := make([]codeOwnersRule, 0)
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		owners := make([]string, 0, len(fields)-1)
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			if strings.HasPrefix(owner, "@") {
+				owners = append(owners, owner)
+			}
+		}
+		rules = append(rules, codeOwnersRule{
+			pattern: gitignore.ParsePattern(fields[0], nil),
+			owners:  owners,
+		})
+	}
+
+	owners := make([]string, 0)
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].matches(segments) {
+				owners = append(owners, rules[i].owners...)
+				break
+			}
+		}
+	}
+
+	return owners, nil
+}
+
+// Reports whether a rule matches a path or any of its parent directories
+func (r codeOwnersRule) matches(segments []string) bool {
+	for i := range segments {
+		if r.pattern.Match(segments[:i+1], i < len(segments)-1) != gitignore.NoMatch {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Removes empty and repeated values, keeping the first occurrence of each
+func uniqueNonEmpty(values []string) []string {
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,413 @@
+package reviewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// This reviewer creates and completes pull requests in Azure Repos on Azure DevOps Services or Azure DevOps Server
+// using the REST API
+type AzureDevOps struct {
+	Token        string       // A personal access token with the Code (Read & Write) scope
+	HTTPClient   *http.Client // Defaults to http.DefaultClient
+	WorkItemsKey string       // The request metadata key holding the IDs of work items to link. Defaults to workItems.
+	MergeOptions *AzureDevOpsMergeOptions
+}
+
+type AzureDevOpsMergeOptions struct {
+	Strategy            string // One of noFastForward, squash, rebase or rebaseMerge. Defaults to noFastForward.
+	CommitMessage       string
+	DeleteSourceBranch  bool
+	TransitionWorkItems bool // Whether to resolve linked work items when the pull request completes
+	AutoComplete        bool // If true, pull requests are set to complete once their policies pass.
+}
+
+const (
+	azureDevOpsAPIVersion     = "7.1"
+	defaultWorkItemsKey       = "workItems"
+	azureDevOpsCompletePolls  = 30
+	azureDevOpsCompleteStatus = "completed"
+)
+
+type azureDevOpsPullRequest struct {
+	PullRequestId         int                  `json:"pullRequestId"`
+	Status                string               `json:"status"`
+	MergeStatus           string               `json:"mergeStatus"`
+	MergeFailureMessage   string               `json:"mergeFailureMessage"`
+	SourceRefName         string               `json:"sourceRefName"`
+	TargetRefName         string               `json:"targetRefName"`
+	CreatedBy             *azureDevOpsIdentity `json:"createdBy"`
+	AutoCompleteSetBy     *azureDevOpsIdentity `json:"autoCompleteSetBy"`
+	LastMergeSourceCommit *azureDevOpsCommit   `json:"lastMergeSourceCommit"`
+}
+
+type azureDevOpsCreatePullRequest struct {
+	SourceRefName string                `json:"sourceRefName"`
+	TargetRefName string                `json:"targetRefName"`
+	Title         string                `json:"title"`
+	Description   string                `json:"description"`
+	WorkItemRefs  []azureDevOpsResource `json:"workItemRefs,omitempty"`
+}
+
+type azureDevOpsUpdatePullRequest struct {
+	Status                string                        `json:"status,omitempty"`
+	AutoCompleteSetBy     *azureDevOpsIdentity          `json:"autoCompleteSetBy,omitempty"`
+	LastMergeSourceCommit *azureDevOpsCommit            `json:"lastMergeSourceCommit,omitempty"`
+	CompletionOptions     *azureDevOpsCompletionOptions `json:"completionOptions,omitempty"`
+}
+
+type azureDevOpsCompletionOptions struct {
+	MergeStrategy       string `json:"mergeStrategy,omitempty"`
+	MergeCommitMessage  string `json:"mergeCommitMessage,omitempty"`
+	DeleteSourceBranch  bool   `json:"deleteSourceBranch"`
+	TransitionWorkItems bool   `json:"transitionWorkItems"`
+}
+
+type azureDevOpsIdentity struct {
+	ID string `json:"id"`
+}
+
+type azureDevOpsCommit struct {
+	CommitId string `json:"commitId"`
+}
+
+type azureDevOpsResource struct {
+	ID string `json:"id"`
+}
+
+type azureDevOpsResponseError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *azureDevOpsResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("received %d status code", e.StatusCode)
+	}
+
+	return fmt.Sprintf("received %d status code: %s", e.StatusCode, e.Message)
+}
+
+// Identifies a repository by the URL of its collection, its project and its name
+type azureDevOpsRepository struct {
+	CollectionURL string // https://dev.azure.com/{organisation} or the collection URL of Azure DevOps Server
+	Project       string
+	Repository    string
+}
+
+func (r *azureDevOpsRepository) apiPath() string {
+	return fmt.Sprintf("%s/%s/_apis/git/repositories/%s", r.CollectionURL, url.PathEscape(r.Project), url.PathEscape(r.Repository))
+}
+
+func (r *azureDevOpsRepository) pullRequestURL(pullRequestId int) string {
+	return fmt.Sprintf("%s/%s/_git/%s/pullrequest/%d", r.CollectionURL, url.PathEscape(r.Project), url.PathEscape(r.Repository), pullRequestId)
+}
+
+func (a *AzureDevOps) CreateReview(ctx context.Context, req *gitops.Request, target *gitops.Target, sendMsg func(string)) (*gitops.CreateReviewResult, error) {
+	repository, err := a.getRepository(target.Repository.URL)
+	if err != nil {
+		return nil, err
+	}
+	sourceRef := "refs/heads/" + target.Branch.Source
+	targetRef := "refs/heads/" + target.Branch.Target
+
+	slog.Info("listing pull requests to find existing", "project", repository.Project, "repository", repository.Repository)
+	pullRequests := &struct {
+		Value []*azureDevOpsPullRequest `json:"value"`
+	}{}
+	_, err = a.do(ctx, http.MethodGet, repository.apiPath()+"/pullrequests", url.Values{
+		"searchCriteria.status":        {"active"},
+		"searchCriteria.sourceRefName": {sourceRef},
+		"searchCriteria.targetRefName": {targetRef},
+	}, nil, pullRequests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository pull requests: %w", err)
+	}
+
+	var pullRequest *azureDevOpsPullRequest
+	if len(pullRequests.Value) != 0 {
+		pullRequest = pullRequests.Value[0]
+		sendMsg("pull request already exists")
+	} else {
+		workItems, err := a.workItems(req)
+		if err != nil {
+			return nil, err
+		}
+		content, err := reviewContent(req, target)
+		if err != nil {
+			return nil, err
+		}
+
+		createOptions := &azureDevOpsCreatePullRequest{
+			SourceRefName: sourceRef,
+			TargetRefName: targetRef,
+			Title:         content.Title,
+			Description:   content.Description,
+		}
+		for _, workItem := range workItems {
+			createOptions.WorkItemRefs = append(createOptions.WorkItemRefs, azureDevOpsResource{ID: workItem})
+		}
+
+		pullRequest = &azureDevOpsPullRequest{}
+		statusCode, err := a.do(ctx, http.MethodPost, repository.apiPath()+"/pullrequests", nil, createOptions, pullRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pull request: %w", err)
+		}
+		if statusCode != http.StatusCreated {
+			sendMsg(fmt.Sprintf("received %d status code", statusCode))
+			return nil, fmt.Errorf("did not receieve 201 CREATED status code")
+		}
+		if len(workItems) > 0 {
+			sendMsg(fmt.Sprintf("linked work items %s", strings.Join(workItems, ", ")))
+		}
+	}
+
+	if a.mergeOptions().AutoComplete && pullRequest.AutoCompleteSetBy == nil && pullRequest.CreatedBy != nil {
+		_, err = a.do(ctx, http.MethodPatch, fmt.Sprintf("%s/pullrequests/%d", repository.apiPath(), pullRequest.PullRequestId), nil, &azureDevOpsUpdatePullRequest{
+			AutoCompleteSetBy: pullRequest.CreatedBy,
+			CompletionOptions: a.completionOptions(),
+		}, pullRequest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set auto-complete: %w", err)
+		}
+		sendMsg("auto-complete set")
+	}
+
+	result := &gitops.CreateReviewResult{
+		Created:   true,
+		URL:       repository.pullRequestURL(pullRequest.PullRequestId),
+		Completed: pullRequest.Status == azureDevOpsCompleteStatus,
+	}
+	slog.Info("created pull request", "result", result)
+
+	return result, nil
+}
+
+func (a *AzureDevOps) CompleteReview(ctx context.Context, req *gitops.Request, createReviewResult *gitops.CreateReviewResult, sendMsg func(string)) (bool, error) {
+	repository, pullRequestId, err := a.getRepositoryAndPullRequestId(createReviewResult.URL)
+	if err != nil {
+		return false, err
+	}
+	slog.Debug("parsed azure devops ids", "project", repository.Project, "repository", repository.Repository, "pullRequestId", pullRequestId)
+	pullRequestPath := fmt.Sprintf("%s/pullrequests/%d", repository.apiPath(), pullRequestId)
+
+	pullRequest := &azureDevOpsPullRequest{}
+	_, err = a.do(ctx, http.MethodGet, pullRequestPath, nil, nil, pullRequest)
+	if err != nil {
+		return false, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	if pullRequest.Status != azureDevOpsCompleteStatus {
+		sendMsg("completing pull request")
+		_, err = a.do(ctx, http.MethodPatch, pullRequestPath, nil, &azureDevOpsUpdatePullRequest{
+			Status:                azureDevOpsCompleteStatus,
+			LastMergeSourceCommit: pullRequest.LastMergeSourceCommit,
+			CompletionOptions:     a.completionOptions(),
+		}, pullRequest)
+		if err != nil {
+			return false, fmt.Errorf("failed to complete pull request: %w", err)
+		}
+	}
+
+	// Pull requests are merged asynchronously after being completed
+	for polls := 0; pullRequest.Status != azureDevOpsCompleteStatus; polls++ {
+		switch pullRequest.MergeStatus {
+		case "conflicts", "failure", "rejectedByPolicy":
+			return false, fmt.Errorf("failed to merge pull request with merge status %s: %s", pullRequest.MergeStatus, pullRequest.MergeFailureMessage)
+		}
+		if polls == azureDevOpsCompletePolls {
+			return false, fmt.Errorf("pull request did not complete with merge status %s", pullRequest.MergeStatus)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(1 * time.Second):
+		}
+
+		_, err = a.do(ctx, http.MethodGet, pullRequestPath, nil, nil, pullRequest)
+		if err != nil {
+			return false, fmt.Errorf("failed to get pull request: %w", err)
+		}
+	}
+	sendMsg("pull request completed")
+
+	return true, nil
+}
+
+func (a *AzureDevOps) mergeOptions() *AzureDevOpsMergeOptions {
+	if a.MergeOptions == nil {
+		return &AzureDevOpsMergeOptions{}
+	}
+
+	return a.MergeOptions
+}
+
+func (a *AzureDevOps) completionOptions() *azureDevOpsCompletionOptions {
+	mergeOptions := a.mergeOptions()
+
+	return &azureDevOpsCompletionOptions{
+		MergeStrategy:       mergeOptions.Strategy,
+		MergeCommitMessage:  mergeOptions.CommitMessage,
+		DeleteSourceBranch:  mergeOptions.DeleteSourceBranch,
+		TransitionWorkItems: mergeOptions.TransitionWorkItems,
+	}
+}
+
+// Reads work item IDs from the request metadata. The value may be a list of IDs or a string of IDs separated by commas
+// or spaces, each optionally prefixed with # or AB#.
+func (a *AzureDevOps) workItems(req *gitops.Request) ([]string, error) {
+	key := a.WorkItemsKey
+	if key == "" {
+		key = defaultWorkItemsKey
+	}
+
+	var values []any
+	switch value := req.Metadata[key].(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		values = value
+	case string:
+		for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			values = append(values, field)
+		}
+	default:
+		values = []any{value}
+	}
+
+	workItems := make([]string, 0, len(values))
+	for _, value := range values {
+		id := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(fmt.Sprint(value)), "AB"), "#")
+		_, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid work item ID %v in request metadata %s", value, key)
+		}
+		workItems = append(workItems, id)
+	}
+
+	return workItems, nil
+}
+
+// Sends a request to the REST API, decoding a successful response into out. Unsuccessful responses are returned as a
+// *azureDevOpsResponseError.
+func (a *AzureDevOps) do(ctx context.Context, method, endpoint string, query url.Values, body any, out any) (int, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("api-version", azureDevOpsAPIVersion)
+	endpoint += "?" + query.Encode()
+
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, requestBody)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if a.Token != "" {
+		request.SetBasicAuth("", a.Token)
+	}
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseErr := &azureDevOpsResponseError{StatusCode: response.StatusCode}
+		errorBody := &struct {
+			Message string `json:"message"`
+		}{}
+		json.NewDecoder(response.Body).Decode(errorBody)
+		responseErr.Message = errorBody.Message
+		return response.StatusCode, responseErr
+	}
+
+	if out != nil && response.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(response.Body).Decode(out)
+		if err != nil {
+			return response.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return response.StatusCode, nil
+}
+
+// Parses the collection, project and repository from a URL such as https://dev.azure.com/org/project/_git/repo,
+// https://org.visualstudio.com/project/_git/repo or https://server/tfs/collection/project/_git/repo
+func (a *AzureDevOps) getRepository(repositoryUrl string) (*azureDevOpsRepository, error) {
+	targetRepositoryUrl, err := url.Parse(repositoryUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse target repository URL: %w", err)
+	}
+
+	collectionProject, repo, found := strings.Cut(strings.TrimSuffix(targetRepositoryUrl.Path, "/"), "/_git/")
+	if !found {
+		return nil, fmt.Errorf("failed to find repository in URL %s", repositoryUrl)
+	}
+	collectionPath, project, found := cutLast(collectionProject, "/")
+	if !found || project == "" {
+		return nil, fmt.Errorf("failed to find project in URL %s", repositoryUrl)
+	}
+	repo, _, _ = strings.Cut(repo, "/")
+
+	return &azureDevOpsRepository{
+		CollectionURL: fmt.Sprintf("%s://%s%s", targetRepositoryUrl.Scheme, targetRepositoryUrl.Host, collectionPath),
+		Project:       project,
+		Repository:    strings.TrimSuffix(repo, ".git"),
+	}, nil
+}
+
+func (a *AzureDevOps) getRepositoryAndPullRequestId(pullRequestUrl string) (*azureDevOpsRepository, int, error) {
+	repositoryUrl, pullRequest, found := strings.Cut(pullRequestUrl, "/pullrequest/")
+	if !found {
+		return nil, 0, fmt.Errorf("failed to find pull request ID in URL %s", pullRequestUrl)
+	}
+
+	repository, err := a.getRepository(repositoryUrl)
+	if err != nil {
+		return nil, 0, err
+	}
+	pullRequestId, err := strconv.Atoi(strings.SplitN(pullRequest, "/", 2)[0])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse ID from pull request URL: %w", err)
+	}
+
+	return repository, pullRequestId, nil
+}
+
+func cutLast(s, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+len(sep):], true
+}
@@ -0,0 +1,461 @@
+package reviewer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// This reviewer creates and merges pull requests on Bitbucket Server and Bitbucket Data Center using the REST API
+type Bitbucket struct {
+	BaseURL          string       // The URL of the server including any context path, e.g. https://bitbucket.example.com
+	Token            string       // An HTTP access token or personal access token sent as a bearer token
+	HTTPClient       *http.Client // Defaults to http.DefaultClient
+	Reviewers        []string     // User names added as reviewers to created pull requests
+	DefaultReviewers bool         // Whether to add the default reviewers configured for the repository to created pull requests
+	MergeOptions     *BitbucketMergeOptions
+}
+
+type BitbucketMergeOptions struct {
+	Strategy      string // A merge strategy enabled for the repository, such as no-ff, squash or rebase-ff-only. Defaults to the repository default.
+	CommitMessage string
+	DeleteBranch  bool
+}
+
+// Returned when the merge checks of the server veto merging a pull request
+type BitbucketMergeVetoedError struct {
+	URL    string
+	Vetoes []BitbucketVeto
+}
+
+type BitbucketVeto struct {
+	SummaryMessage  string `json:"summaryMessage"`
+	DetailedMessage string `json:"detailedMessage"`
+}
+
+func (e *BitbucketMergeVetoedError) Error() string {
+	messages := make([]string, 0, len(e.Vetoes))
+	for _, veto := range e.Vetoes {
+		message := veto.SummaryMessage
+		if veto.DetailedMessage != "" {
+			message = fmt.Sprintf("%s: %s", message, veto.DetailedMessage)
+		}
+		messages = append(messages, message)
+	}
+
+	return fmt.Sprintf("merging %s was vetoed by merge checks: %s", e.URL, strings.Join(messages, "; "))
+}
+
+type bitbucketPullRequest struct {
+	ID      int          `json:"id"`
+	Version int          `json:"version"`
+	State   string       `json:"state"`
+	FromRef bitbucketRef `json:"fromRef"`
+	ToRef   bitbucketRef `json:"toRef"`
+	Links   struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+type bitbucketCreatePullRequest struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	FromRef     bitbucketRef        `json:"fromRef"`
+	ToRef       bitbucketRef        `json:"toRef"`
+	Reviewers   []bitbucketReviewer `json:"reviewers,omitempty"`
+}
+
+type bitbucketReviewer struct {
+	User bitbucketUser `json:"user"`
+}
+
+type bitbucketRef struct {
+	ID string `json:"id"`
+}
+
+type bitbucketUser struct {
+	Name string `json:"name"`
+}
+
+type bitbucketPage[T any] struct {
+	Values        []T  `json:"values"`
+	IsLastPage    bool `json:"isLastPage"`
+	NextPageStart int  `json:"nextPageStart"`
+}
+
+type bitbucketErrors struct {
+	Errors []struct {
+		Message       string          `json:"message"`
+		ExceptionName string          `json:"exceptionName"`
+		Vetoes        []BitbucketVeto `json:"vetoes"`
+	} `json:"errors"`
+}
+
+type bitbucketResponseError struct {
+	StatusCode int
+	Body       bitbucketErrors
+}
+
+func (e *bitbucketResponseError) Error() string {
+	messages := make([]string, 0, len(e.Body.Errors))
+	for _, err := range e.Body.Errors {
+		messages = append(messages, err.Message)
+	}
+	if len(messages) == 0 {
+		return fmt.Sprintf("received %d status code", e.StatusCode)
+	}
+
+	return fmt.Sprintf("received %d status code: %s", e.StatusCode, strings.Join(messages, "; "))
+}
+
+func (e *bitbucketResponseError) hasException(name string) bool {
+	for _, err := range e.Body.Errors {
+		if strings.HasSuffix(err.ExceptionName, "."+name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *bitbucketResponseError) vetoes() []BitbucketVeto {
+	vetoes := make([]BitbucketVeto, 0)
+	for _, err := range e.Body.Errors {
+		vetoes = append(vetoes, err.Vetoes...)
+	}
+
+	return vetoes
+}
+
+func (b *Bitbucket) CreateReview(ctx context.Context, req *gitops.Request, target *gitops.Target, sendMsg func(string)) (*gitops.CreateReviewResult, error) {
+	project, repo, err := b.getProjectRepo(target.Repository.URL)
+	if err != nil {
+		return nil, err
+	}
+	repoPath := fmt.Sprintf("/rest/api/1.0/%s/repos/%s", projectPath(project), url.PathEscape(repo))
+	sourceRef := "refs/heads/" + target.Branch.Source
+	targetRef := "refs/heads/" + target.Branch.Target
+
+	slog.Info("listing pull requests to find existing", "project", project, "repo", repo)
+	var pullRequest *bitbucketPullRequest
+	start := 0
+	for pullRequest == nil {
+		page := &bitbucketPage[*bitbucketPullRequest]{}
+		_, err := b.do(ctx, http.MethodGet, repoPath+"/pull-requests", url.Values{
+			"state":     {"OPEN"},
+			"direction": {"OUTGOING"},
+			"at":        {sourceRef},
+			"start":     {strconv.Itoa(start)},
+			"limit":     {"100"},
+		}, nil, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository pull requests: %w", err)
+		}
+
+		for _, pr := range page.Values {
+			slog.Debug("checking pull request for environment match", "fromRef", pr.FromRef.ID, "toRef", pr.ToRef.ID)
+			if pr.FromRef.ID == sourceRef && pr.ToRef.ID == targetRef {
+				pullRequest = pr
+				break
+			}
+		}
+
+		if page.IsLastPage {
+			break
+		}
+		start = page.NextPageStart
+	}
+
+	if pullRequest != nil {
+		sendMsg("pull request already exists")
+		return bitbucketResult(pullRequest), nil
+	}
+
+	reviewers, err := b.reviewers(ctx, repoPath, project, repo, sourceRef, targetRef)
+	if err != nil {
+		return nil, err
+	}
+	content, err := reviewContent(req, target)
+	if err != nil {
+		return nil, err
+	}
+
+	createOptions := &bitbucketCreatePullRequest{
+		Title:       content.Title,
+		Description: content.Description,
+		FromRef:     bitbucketRef{ID: sourceRef},
+		ToRef:       bitbucketRef{ID: targetRef},
+	}
+	for _, reviewer := range reviewers {
+		createOptions.Reviewers = append(createOptions.Reviewers, bitbucketReviewer{User: bitbucketUser{Name: reviewer}})
+	}
+
+	created := &bitbucketPullRequest{}
+	statusCode, err := b.do(ctx, http.MethodPost, repoPath+"/pull-requests", nil, createOptions, created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	if statusCode != http.StatusCreated {
+		sendMsg(fmt.Sprintf("received %d status code", statusCode))
+		return nil, fmt.Errorf("did not receieve 201 CREATED status code")
+	}
+
+	result := bitbucketResult(created)
+	slog.Info("created pull request", "result", result)
+
+	return result, nil
+}
+
+func (b *Bitbucket) CompleteReview(ctx context.Context, req *gitops.Request, createReviewResult *gitops.CreateReviewResult, sendMsg func(string)) (bool, error) {
+	project, repo, pullRequestId, err := b.getProjectRepoPullRequestId(createReviewResult.URL)
+	if err != nil {
+		return false, err
+	}
+	repoPath := fmt.Sprintf("/rest/api/1.0/%s/repos/%s", projectPath(project), url.PathEscape(repo))
+	pullRequestPath := fmt.Sprintf("%s/pull-requests/%d", repoPath, pullRequestId)
+
+	mergeOptions := b.MergeOptions
+	if mergeOptions == nil {
+		mergeOptions = &BitbucketMergeOptions{}
+	}
+	mergeBody := map[string]string{}
+	if mergeOptions.Strategy != "" {
+		mergeBody["strategyId"] = mergeOptions.Strategy
+	}
+	if mergeOptions.CommitMessage != "" {
+		mergeBody["message"] = mergeOptions.CommitMessage
+	}
+
+	sendMsg("merging pull request")
+	pullRequest := &bitbucketPullRequest{}
+	retries := 0
+	retryLimit := 3
+Merge:
+	_, err = b.do(ctx, http.MethodGet, pullRequestPath, nil, nil, pullRequest)
+	if err != nil {
+		return false, fmt.Errorf("failed to get pull request: %w", err)
+	}
+	if pullRequest.State != "MERGED" {
+		// The version guards against merging changes pushed since the pull request was read
+		_, err = b.do(ctx, http.MethodPost, pullRequestPath+"/merge", url.Values{"version": {strconv.Itoa(pullRequest.Version)}}, mergeBody, pullRequest)
+		var responseErr *bitbucketResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusConflict {
+			if responseErr.hasException("PullRequestOutOfDateException") && retries < retryLimit {
+				timer := time.NewTimer(1 * time.Second)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return false, fmt.Errorf("stopped retrying the merge: %w", ctx.Err())
+				case <-timer.C:
+				}
+				retries++
+				goto Merge
+			}
+			if vetoes := responseErr.vetoes(); len(vetoes) > 0 {
+				for _, veto := range vetoes {
+					sendMsg(fmt.Sprintf("merge check failed: %s", veto.SummaryMessage))
+				}
+				return false, &BitbucketMergeVetoedError{URL: createReviewResult.URL, Vetoes: vetoes}
+			}
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to merge pull request: %w", err)
+		}
+	}
+	merged := pullRequest.State == "MERGED"
+	if !merged {
+		return false, fmt.Errorf("failed to merge for an unknown reason")
+	}
+	sendMsg("pull request merged")
+
+	if mergeOptions.DeleteBranch {
+		_, err = b.do(ctx, http.MethodDelete, fmt.Sprintf("/rest/branch-utils/1.0/%s/repos/%s/branches", projectPath(project), url.PathEscape(repo)), nil, map[string]any{
+			"name":   pullRequest.FromRef.ID,
+			"dryRun": false,
+		}, nil)
+		if err != nil {
+			return merged, fmt.Errorf("failed to delete source branch: %w", err)
+		}
+		sendMsg("source branch deleted")
+	}
+
+	return merged, nil
+}
+
+// Returns the configured reviewers together with the default reviewers of the repository if enabled
+func (b *Bitbucket) reviewers(ctx context.Context, repoPath, project, repo, sourceRef, targetRef string) ([]string, error) {
+	reviewers := slices.Clone(b.Reviewers)
+	if !b.DefaultReviewers {
+		return reviewers, nil
+	}
+
+	repository := &struct {
+		ID int `json:"id"`
+	}{}
+	_, err := b.do(ctx, http.MethodGet, repoPath, nil, nil, repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+
+	defaultReviewers := make([]bitbucketUser, 0)
+	repositoryId := strconv.Itoa(repository.ID)
+	_, err = b.do(ctx, http.MethodGet, fmt.Sprintf("/rest/default-reviewers/1.0/%s/repos/%s/reviewers", projectPath(project), url.PathEscape(repo)), url.Values{
+		"sourceRepoId": {repositoryId},
+		"targetRepoId": {repositoryId},
+		"sourceRefId":  {sourceRef},
+		"targetRefId":  {targetRef},
+	}, nil, &defaultReviewers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default reviewers: %w", err)
+	}
+
+	for _, user := range defaultReviewers {
+		if !slices.Contains(reviewers, user.Name) {
+			reviewers = append(reviewers, user.Name)
+		}
+	}
+
+	return reviewers, nil
+}
+
+// Sends a request to the REST API, decoding a successful response into out. Unsuccessful responses are returned as a
+// *bitbucketResponseError.
+func (b *Bitbucket) do(ctx context.Context, method, path string, query url.Values, body any, out any) (int, error) {
+	endpoint := strings.TrimSuffix(b.BaseURL, "/") + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, requestBody)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if b.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+b.Token)
+	}
+	// Required by the server for requests which would otherwise be blocked by XSRF protection
+	request.Header.Set("X-Atlassian-Token", "no-check")
+
+	client := b.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseErr := &bitbucketResponseError{StatusCode: response.StatusCode}
+		json.NewDecoder(response.Body).Decode(&responseErr.Body)
+		return response.StatusCode, responseErr
+	}
+
+	if out != nil && response.StatusCode != http.StatusNoContent {
+		err = json.NewDecoder(response.Body).Decode(out)
+		if err != nil {
+			return response.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return response.StatusCode, nil
+}
+
+// Parses the project key and repository slug from a clone URL such as https://bitbucket.example.com/scm/PROJ/repo.git
+// or a browse URL such as https://bitbucket.example.com/projects/PROJ/repos/repo. Personal repositories have a project
+// key of ~user.
+func (b *Bitbucket) getProjectRepo(repositoryUrl string) (string, string, error) {
+	targetRepositoryUrl, err := url.Parse(repositoryUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse target repository URL: %w", err)
+	}
+
+	pathSegments := strings.Split(strings.Trim(targetRepositoryUrl.Path, "/"), "/")
+	for i, segment := range pathSegments {
+		if i+2 < len(pathSegments) && segment == "scm" {
+			return pathSegments[i+1], strings.TrimSuffix(pathSegments[i+2], ".git"), nil
+		}
+		if i+3 < len(pathSegments) && pathSegments[i+2] == "repos" {
+			switch segment {
+			case "projects":
+				return pathSegments[i+1], pathSegments[i+3], nil
+			case "users":
+				return "~" + pathSegments[i+1], pathSegments[i+3], nil
+			}
+		}
+	}
+
+	if len(pathSegments) < 2 {
+		return "", "", fmt.Errorf("failed to find project and repository in URL %s", repositoryUrl)
+	}
+
+	// SSH clone URLs have the form ssh://git@bitbucket.example.com:7999/proj/repo.git
+	return pathSegments[len(pathSegments)-2], strings.TrimSuffix(pathSegments[len(pathSegments)-1], ".git"), nil
+}
+
+func (b *Bitbucket) getProjectRepoPullRequestId(pullRequestUrl string) (string, string, int, error) {
+	project, repo, err := b.getProjectRepo(pullRequestUrl)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	_, after, found := strings.Cut(pullRequestUrl, "/pull-requests/")
+	if !found {
+		return "", "", 0, fmt.Errorf("failed to find pull request ID in URL %s", pullRequestUrl)
+	}
+	pullRequestId, err := strconv.Atoi(strings.SplitN(after, "/", 2)[0])
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to parse ID from pull request URL: %w", err)
+	}
+
+	return project, repo, pullRequestId, nil
+}
+
+// Returns the API path of a project, which differs for personal projects
+func projectPath(project string) string {
+	if user, found := strings.CutPrefix(project, "~"); found {
+		return "users/" + url.PathEscape(user)
+	}
+
+	return "projects/" + url.PathEscape(project)
+}
+
+func bitbucketResult(pullRequest *bitbucketPullRequest) *gitops.CreateReviewResult {
+	result := &gitops.CreateReviewResult{
+		Created:   true,
+		Completed: pullRequest.State == "MERGED",
+	}
+	if len(pullRequest.Links.Self) > 0 {
+		result.URL = pullRequest.Links.Self[0].Href
+	}
+
+	return result
+}
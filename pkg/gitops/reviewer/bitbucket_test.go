@@ -0,0 +1,365 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+const (
+	bitbucketTestRepoPath       = "/rest/api/1.0/projects/OPS/repos/config"
+	bitbucketTestSourceRef      = "refs/heads/next/dev/app/1"
+	bitbucketTestTargetRef      = "refs/heads/main"
+	bitbucketTestPullRequestURL = "/projects/OPS/repos/config/pull-requests/5"
+)
+
+// Starts a stand-in for the Bitbucket REST API which fails the test on any request without the bearer token
+func newBitbucketTestServer(t *testing.T, mux *http.ServeMux) (*Bitbucket, *gitops.Target) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("%s %s: missing bearer token", r.Method, r.URL.Path)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	reviewer := &Bitbucket{
+		BaseURL:    server.URL,
+		Token:      "token",
+		HTTPClient: server.Client(),
+	}
+	target := &gitops.Target{
+		Repository: gitops.Repository{URL: server.URL + "/scm/OPS/config.git"},
+		Branch: gitops.TargetBranch{
+			Source: "next/dev/app/1",
+			Target: "main",
+		},
+	}
+
+	return reviewer, target
+}
+
+func newBitbucketTestRequest() *gitops.Request {
+	return &gitops.Request{
+		Environment:      "dev",
+		AppName:          "app",
+		UpdateIdentifier: "1",
+		Review: &gitops.ReviewContent{
+			Title:       "Update app in dev",
+			Description: "Rendered manifests",
+		},
+	}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, statusCode int, body any) {
+	t.Helper()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		t.Errorf("failed to encode response: %s", err)
+	}
+}
+
+func bitbucketTestPullRequest(serverURL string, id, version int, state string) map[string]any {
+	return map[string]any{
+		"id":      id,
+		"version": version,
+		"state":   state,
+		"fromRef": map[string]any{"id": bitbucketTestSourceRef},
+		"toRef":   map[string]any{"id": bitbucketTestTargetRef},
+		"links": map[string]any{
+			"self": []map[string]any{{"href": fmt.Sprintf("%s/projects/OPS/repos/config/pull-requests/%d", serverURL, id)}},
+		},
+	}
+}
+
+func TestBitbucketCreateReviewFindsExistingPullRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, target := newBitbucketTestServer(t, mux)
+
+	pages := 0
+	mux.HandleFunc("GET "+bitbucketTestRepoPath+"/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		query := r.URL.Query()
+		if query.Get("at") != bitbucketTestSourceRef || query.Get("state") != "OPEN" || query.Get("direction") != "OUTGOING" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		switch query.Get("start") {
+		case "0":
+			other := bitbucketTestPullRequest(reviewer.BaseURL, 4, 0, "OPEN")
+			other["toRef"] = map[string]any{"id": "refs/heads/release"}
+			writeJSON(t, w, http.StatusOK, map[string]any{"values": []any{other}, "isLastPage": false, "nextPageStart": 25})
+		case "25":
+			writeJSON(t, w, http.StatusOK, map[string]any{"values": []any{bitbucketTestPullRequest(reviewer.BaseURL, 5, 0, "OPEN")}, "isLastPage": true})
+		default:
+			t.Errorf("unexpected page start %s", query.Get("start"))
+		}
+	})
+	mux.HandleFunc("POST "+bitbucketTestRepoPath+"/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("created a pull request although one exists")
+	})
+
+	result, err := reviewer.CreateReview(context.Background(), newBitbucketTestRequest(), target, func(string) {})
+	if err != nil {
+		t.Fatalf("CreateReview() error = %s", err)
+	}
+
+	if pages != 2 {
+		t.Errorf("listed %d pages, want 2", pages)
+	}
+	if result.URL != reviewer.BaseURL+bitbucketTestPullRequestURL {
+		t.Errorf("URL = %s, want the existing pull request", result.URL)
+	}
+	if result.Completed {
+		t.Error("Completed = true for an open pull request")
+	}
+}
+
+func TestBitbucketCreateReviewAddsDefaultReviewers(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, target := newBitbucketTestServer(t, mux)
+	reviewer.Reviewers = []string{"alice"}
+	reviewer.DefaultReviewers = true
+
+	mux.HandleFunc("GET "+bitbucketTestRepoPath+"/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusOK, map[string]any{"values": []any{}, "isLastPage": true})
+	})
+	mux.HandleFunc("GET "+bitbucketTestRepoPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, http.StatusOK, map[string]any{"id": 42, "slug": "config"})
+	})
+	mux.HandleFunc("GET /rest/default-reviewers/1.0/projects/OPS/repos/config/reviewers", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("sourceRepoId") != "42" || query.Get("targetRepoId") != "42" ||
+			query.Get("sourceRefId") != bitbucketTestSourceRef || query.Get("targetRefId") != bitbucketTestTargetRef {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		writeJSON(t, w, http.StatusOK, []map[string]any{{"name": "bob"}, {"name": "alice"}})
+	})
+
+	var created bitbucketCreatePullRequest
+	mux.HandleFunc("POST "+bitbucketTestRepoPath+"/pull-requests", func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewDecoder(r.Body).Decode(&created)
+		if err != nil {
+			t.Errorf("failed to decode request body: %s", err)
+		}
+		writeJSON(t, w, http.StatusCreated, bitbucketTestPullRequest(reviewer.BaseURL, 5, 0, "OPEN"))
+	})
+
+	result, err := reviewer.CreateReview(context.Background(), newBitbucketTestRequest(), target, func(string) {})
+	if err != nil {
+		t.Fatalf("CreateReview() error = %s", err)
+	}
+
+	if created.Title != "Update app in dev" || created.Description != "Rendered manifests" {
+		t.Errorf("created pull request with title %q and description %q", created.Title, created.Description)
+	}
+	if created.FromRef.ID != bitbucketTestSourceRef || created.ToRef.ID != bitbucketTestTargetRef {
+		t.Errorf("created pull request from %s to %s", created.FromRef.ID, created.ToRef.ID)
+	}
+	reviewers := make([]string, 0, len(created.Reviewers))
+	for _, reviewer := range created.Reviewers {
+		reviewers = append(reviewers, reviewer.User.Name)
+	}
+	if !slices.Equal(reviewers, []string{"alice", "bob"}) {
+		t.Errorf("reviewers = %v, want [alice bob]", reviewers)
+	}
+	if !result.Created || result.URL != reviewer.BaseURL+bitbucketTestPullRequestURL {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// Serves a pull request whose version is bumped by each merge attempt answered with the given errors, after which the
+// merge succeeds
+type bitbucketMergeStandIn struct {
+	t             *testing.T
+	serverURL     string
+	version       int
+	mergeErrors   []map[string]any
+	mergeVersions []string
+	mergeBodies   []map[string]string
+	deletedBranch string
+}
+
+func (s *bitbucketMergeStandIn) register(mux *http.ServeMux) {
+	pullRequestPath := bitbucketTestRepoPath + "/pull-requests/5"
+	state := "OPEN"
+
+	mux.HandleFunc("GET "+pullRequestPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(s.t, w, http.StatusOK, bitbucketTestPullRequest(s.serverURL, 5, s.version, state))
+	})
+	mux.HandleFunc("POST "+pullRequestPath+"/merge", func(w http.ResponseWriter, r *http.Request) {
+		s.mergeVersions = append(s.mergeVersions, r.URL.Query().Get("version"))
+		body := map[string]string{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			s.t.Errorf("failed to decode merge body: %s", err)
+		}
+		s.mergeBodies = append(s.mergeBodies, body)
+
+		if len(s.mergeErrors) > 0 {
+			response := s.mergeErrors[0]
+			s.mergeErrors = s.mergeErrors[1:]
+			s.version++
+			writeJSON(s.t, w, http.StatusConflict, response)
+			return
+		}
+		state = "MERGED"
+		writeJSON(s.t, w, http.StatusOK, bitbucketTestPullRequest(s.serverURL, 5, s.version+1, state))
+	})
+	mux.HandleFunc("DELETE /rest/branch-utils/1.0/projects/OPS/repos/config/branches", func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			s.t.Errorf("failed to decode delete body: %s", err)
+		}
+		s.deletedBranch, _ = body["name"].(string)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func bitbucketTestException(name string, vetoes ...BitbucketVeto) map[string]any {
+	return map[string]any{
+		"errors": []map[string]any{{
+			"message":       name + " was raised",
+			"exceptionName": "com.atlassian.bitbucket.pull." + name,
+			"vetoes":        vetoes,
+		}},
+	}
+}
+
+func TestBitbucketCompleteReviewMergesWithStrategy(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, _ := newBitbucketTestServer(t, mux)
+	reviewer.MergeOptions = &BitbucketMergeOptions{
+		Strategy:      "squash",
+		CommitMessage: "Merge via gitops-manager",
+		DeleteBranch:  true,
+	}
+	standIn := &bitbucketMergeStandIn{t: t, serverURL: reviewer.BaseURL, version: 3}
+	standIn.register(mux)
+
+	merged, err := reviewer.CompleteReview(context.Background(), newBitbucketTestRequest(), &gitops.CreateReviewResult{URL: reviewer.BaseURL + bitbucketTestPullRequestURL}, func(string) {})
+	if err != nil {
+		t.Fatalf("CompleteReview() error = %s", err)
+	}
+
+	if !merged {
+		t.Error("merged = false")
+	}
+	if !slices.Equal(standIn.mergeVersions, []string{"3"}) {
+		t.Errorf("merged with versions %v, want [3]", standIn.mergeVersions)
+	}
+	if body := standIn.mergeBodies[0]; body["strategyId"] != "squash" || body["message"] != "Merge via gitops-manager" {
+		t.Errorf("merge body = %v", body)
+	}
+	if standIn.deletedBranch != bitbucketTestSourceRef {
+		t.Errorf("deleted branch %q, want %s", standIn.deletedBranch, bitbucketTestSourceRef)
+	}
+}
+
+func TestBitbucketCompleteReviewReturnsMergeVetoes(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, _ := newBitbucketTestServer(t, mux)
+	vetoes := []BitbucketVeto{
+		{SummaryMessage: "Not enough approvals", DetailedMessage: "2 approvals are required"},
+		{SummaryMessage: "Build failed"},
+	}
+	standIn := &bitbucketMergeStandIn{
+		t:           t,
+		serverURL:   reviewer.BaseURL,
+		mergeErrors: []map[string]any{bitbucketTestException("PullRequestMergeVetoedException", vetoes...)},
+	}
+	standIn.register(mux)
+
+	messages := make([]string, 0)
+	merged, err := reviewer.CompleteReview(context.Background(), newBitbucketTestRequest(), &gitops.CreateReviewResult{URL: reviewer.BaseURL + bitbucketTestPullRequestURL}, func(msg string) {
+		messages = append(messages, msg)
+	})
+
+	if merged {
+		t.Error("merged = true although the merge was vetoed")
+	}
+	var vetoedErr *BitbucketMergeVetoedError
+	if !errors.As(err, &vetoedErr) {
+		t.Fatalf("error = %v, want a *BitbucketMergeVetoedError", err)
+	}
+	if !slices.Equal(vetoedErr.Vetoes, vetoes) {
+		t.Errorf("vetoes = %v, want %v", vetoedErr.Vetoes, vetoes)
+	}
+	if !slices.Contains(messages, "merge check failed: Not enough approvals") {
+		t.Errorf("messages = %v, want the failed merge checks", messages)
+	}
+	if len(standIn.mergeVersions) != 1 {
+		t.Errorf("attempted %d merges, want 1", len(standIn.mergeVersions))
+	}
+}
+
+func TestBitbucketCompleteReviewRetriesOutOfDateMerge(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, _ := newBitbucketTestServer(t, mux)
+	standIn := &bitbucketMergeStandIn{
+		t:           t,
+		serverURL:   reviewer.BaseURL,
+		version:     7,
+		mergeErrors: []map[string]any{bitbucketTestException("PullRequestOutOfDateException")},
+	}
+	standIn.register(mux)
+
+	merged, err := reviewer.CompleteReview(context.Background(), newBitbucketTestRequest(), &gitops.CreateReviewResult{URL: reviewer.BaseURL + bitbucketTestPullRequestURL}, func(string) {})
+	if err != nil {
+		t.Fatalf("CompleteReview() error = %s", err)
+	}
+
+	if !merged {
+		t.Error("merged = false")
+	}
+	// The pull request is read again after the conflict, so the retry carries the new version
+	if !slices.Equal(standIn.mergeVersions, []string{"7", "8"}) {
+		t.Errorf("merged with versions %v, want [7 8]", standIn.mergeVersions)
+	}
+	if _, ok := standIn.mergeBodies[0]["strategyId"]; ok {
+		t.Error("sent a strategyId without a configured strategy")
+	}
+	if standIn.deletedBranch != "" {
+		t.Error("deleted the source branch without DeleteBranch")
+	}
+}
+
+func TestBitbucketCompleteReviewStopsRetryingWhenCancelled(t *testing.T) {
+	mux := http.NewServeMux()
+	reviewer, _ := newBitbucketTestServer(t, mux)
+	standIn := &bitbucketMergeStandIn{
+		t:           t,
+		serverURL:   reviewer.BaseURL,
+		mergeErrors: []map[string]any{bitbucketTestException("PullRequestOutOfDateException")},
+	}
+	standIn.register(mux)
+
+	// The deadline passes while waiting to retry the merge
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	merged, err := reviewer.CompleteReview(ctx, newBitbucketTestRequest(), &gitops.CreateReviewResult{URL: reviewer.BaseURL + bitbucketTestPullRequestURL}, func(string) {})
+
+	if merged {
+		t.Error("merged = true although the context was cancelled")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context.DeadlineExceeded", err)
+	}
+	if len(standIn.mergeVersions) != 1 {
+		t.Errorf("attempted %d merges, want 1", len(standIn.mergeVersions))
+	}
+}
@@ -0,0 +1,14 @@
+package reviewer
+
+import (
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// Returns the review content populated by the manager, rendering the default templates if there are none
+func reviewContent(req *gitops.Request, target *gitops.Target) (*gitops.ReviewContent, error) {
+	if req.Review != nil {
+		return req.Review, nil
+	}
+
+	return (&gitops.ReviewTemplate{}).Render(gitops.NewReviewTemplateData(req, target))
+}
@@ -4,20 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/url"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"code.gitea.io/sdk/gitea"
 	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	"github.com/tvandinther/gitops-manager/pkg/util"
 )
 
 type Gitea struct {
-	Client       *gitea.Client
-	MergeOptions *gitea.MergePullRequestOption
+	Client          *gitea.Client
+	MergeOptions    *gitea.MergePullRequestOption
+	Assignment      *Assignment  // Labels, assignees, reviewers and a milestone for created pull requests. Labels are also added when pull requests are updated. Missing labels are created.
+	Wait            *WaitOptions // If set, pull requests are merged once approved and their commit statuses pass
+	CommentOnUpdate bool         // Whether to comment on an existing pull request with a summary of the changes when it is updated
 }
 
+const giteaLabelColor = "#ededed"
+
 type GiteaMergeOptions struct {
 	MergeStyle *gitea.MergeStyle
 }
@@ -31,7 +40,7 @@ func (g *Gitea) CreateReview(ctx context.Context, req *gitops.Request, target *g
 	var pullRequest *gitea.PullRequest
 
 	slog.Info("listing pull requests to find existing", "owner", owner, "repo", repo)
-	for pageIndex := 0; true; pageIndex++ {
+	for pageIndex := 1; pullRequest == nil; pageIndex++ {
 		pullRequests, _, err := g.Client.ListRepoPullRequests(owner, repo, gitea.ListPullRequestsOptions{
 			State: gitea.StateOpen,
 			ListOptions: gitea.ListOptions{
@@ -49,45 +58,59 @@ func (g *Gitea) CreateReview(ctx context.Context, req *gitops.Request, target *g
 
 		for _, pr := range pullRequests {
 			slog.Debug("checking pull request for environment match", "baseName", pr.Base.Name, "headName", pr.Head.Name)
-			if pr.Base.Name == target.Branch.Source && pr.Head.Name == target.Branch.Target {
+			if pr.Base.Name == target.Branch.Target && pr.Head.Name == target.Branch.Source {
 				pullRequest = pr
 				break
 			}
 		}
 	}
 
+	content, err := reviewContent(req, target)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := g.Assignment.resolve(req, target)
+	if err != nil {
+		return nil, err
+	}
+
 	if pullRequest != nil {
 		sendMsg("pull Request already exists")
+		assignment, err = g.updatePullRequest(owner, repo, pullRequest, content, assignment, sendMsg)
+		if err != nil {
+			return nil, err
+		}
+
 		return &gitops.CreateReviewResult{
-			Created:   true,
-			URL:       pullRequest.URL,
-			Completed: pullRequest.HasMerged,
+			Created:    true,
+			URL:        pullRequest.URL,
+			Completed:  pullRequest.HasMerged,
+			Assignment: assignment,
 		}, nil
 	}
-
-	pullRequest, response, err := g.Client.CreatePullRequest(owner, repo, gitea.CreatePullRequestOption{
+	createOptions := gitea.CreatePullRequestOption{
 		Head:  target.Branch.Source,
 		Base:  target.Branch.Target,
-		Title: fmt.Sprintf("Promote %s [%s] to %s", req.AppName, req.UpdateIdentifier, req.Environment),
-		Body: fmt.Sprintf(`<table>
-  <tr>
-    <td><strong>Target Environment</strong></td>
-	<td>%s</td>
-  </tr>
-  <tr>
-	<td><strong>Source Repository</strong></td>
-	<td><a href="%s">%s/%s</a></td>
-  </tr>
-  <tr>
-	<td><strong>Source Branch</strong></td>
-	<td><a href="%s/tree/%s">%s</a></td>
-  </tr>
-  <tr>
-	<td><strong>App Name</strong></td>
-	<td>%s</td>
-  </tr>
-</table>`, req.Environment, req.Source.Repository.URL, owner, repo, req.Source.Repository.URL, req.UpdateIdentifier, req.UpdateIdentifier, req.AppName),
-	})
+		Title: content.Title,
+		Body:  content.Description,
+	}
+	if assignment != nil {
+		createOptions.Assignees = assignment.Assignees
+		createOptions.Labels, err = g.getLabelIds(owner, repo, assignment.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if assignment.Milestone != "" {
+			milestone, _, err := g.Client.GetMilestoneByName(owner, repo, assignment.Milestone)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get milestone %q: %w", assignment.Milestone, err)
+			}
+			createOptions.Milestone = milestone.ID
+		}
+	}
+
+	pullRequest, response, err := g.Client.CreatePullRequest(owner, repo, createOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pull request: %w", err)
 	}
@@ -96,10 +119,25 @@ func (g *Gitea) CreateReview(ctx context.Context, req *gitops.Request, target *g
 		return nil, fmt.Errorf("did not receieve 201 CREATED status code")
 	}
 
+	if assignment != nil && (len(assignment.Reviewers) > 0 || len(assignment.TeamReviewers) > 0) {
+		_, err = g.Client.CreateReviewRequests(owner, repo, pullRequest.Index, gitea.PullReviewRequestOptions{
+			Reviewers:     assignment.Reviewers,
+			TeamReviewers: g.teamNames(owner, assignment, sendMsg),
+		})
+		if err != nil {
+			// The pull request exists, so it is not failed for want of reviewers
+			slog.Warn("failed to request reviewers", "error", err)
+			sendMsg(fmt.Sprintf("failed to request reviewers: %s", err))
+			assignment.Reviewers = nil
+			assignment.TeamReviewers = nil
+		}
+	}
+
 	result := &gitops.CreateReviewResult{
-		Created:   true,
-		URL:       pullRequest.URL,
-		Completed: pullRequest.HasMerged,
+		Created:    true,
+		URL:        pullRequest.URL,
+		Completed:  pullRequest.HasMerged,
+		Assignment: assignment,
 	}
 	slog.Info("created pull request", "result", result)
 
@@ -107,19 +145,18 @@ func (g *Gitea) CreateReview(ctx context.Context, req *gitops.Request, target *g
 }
 
 func (g *Gitea) CompleteReview(ctx context.Context, req *gitops.Request, createReviewResult *gitops.CreateReviewResult, sendMsg func(string)) (bool, error) {
-	owner, repo, err := g.getOwnerRepo(createReviewResult.URL)
+	owner, repo, pullRequestId, err := g.getOwnerRepoAndPullRequestId(createReviewResult.URL)
 	if err != nil {
 		return false, err
 	}
 
-	pullRequestUrl, err := url.Parse(createReviewResult.URL)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse pull request URL: %w", err)
-	}
-	pathSegments := strings.Split(pullRequestUrl.Path, "/")
-	pullRequestId, err := strconv.ParseInt(pathSegments[len(pathSegments)-1], 10, 64)
-	if err != nil {
-		return false, fmt.Errorf("failed to parse ID from pull request URL: %w", err)
+	if g.Wait != nil {
+		err = g.Wait.wait(ctx, sendMsg, func(context.Context) (*reviewState, error) {
+			return g.getReviewState(owner, repo, pullRequestId)
+		})
+		if err != nil {
+			return false, err
+		}
 	}
 
 	sendMsg("merging pull request")
@@ -144,6 +181,359 @@ Merge:
 	return merged, nil
 }
 
+func (g *Gitea) ListOpenReviews(ctx context.Context, repository gitops.Repository) ([]*gitops.OpenReview, error) {
+	owner, repo, err := g.getOwnerRepo(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make([]*gitops.OpenReview, 0)
+	for pageIndex := 1; ; pageIndex++ {
+		pullRequests, _, err := g.Client.ListRepoPullRequests(owner, repo, gitea.ListPullRequestsOptions{
+			State: gitea.StateOpen,
+			ListOptions: gitea.ListOptions{
+				Page:     pageIndex,
+				PageSize: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository pull requests: %w", err)
+		}
+		if len(pullRequests) == 0 {
+			break
+		}
+
+		for _, pr := range pullRequests {
+			review := &gitops.OpenReview{
+				URL:          pr.URL,
+				SourceBranch: pr.Head.Ref,
+				TargetBranch: pr.Base.Ref,
+			}
+			if pr.Updated != nil {
+				review.UpdatedAt = *pr.Updated
+			}
+			reviews = append(reviews, review)
+		}
+	}
+
+	return reviews, nil
+}
+
+func (g *Gitea) CloseReview(ctx context.Context, review *gitops.OpenReview, comment string) error {
+	owner, repo, pullRequestId, err := g.getOwnerRepoAndPullRequestId(review.URL)
+	if err != nil {
+		return err
+	}
+
+	if comment != "" {
+		_, _, err = g.Client.CreateIssueComment(owner, repo, pullRequestId, gitea.CreateIssueCommentOption{
+			Body: comment,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to comment on pull request: %w", err)
+		}
+	}
+
+	// Pull requests are closed as issues, as editing a pull request would also replace its body
+	closed := gitea.StateClosed
+	_, _, err = g.Client.EditIssue(owner, repo, pullRequestId, gitea.EditIssueOption{
+		State: &closed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to close pull request: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Gitea) ListBranches(ctx context.Context, repository gitops.Repository) ([]*gitops.RepositoryBranch, error) {
+	owner, repo, err := g.getOwnerRepo(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make([]*gitops.RepositoryBranch, 0)
+	for pageIndex := 1; ; pageIndex++ {
+		repoBranches, _, err := g.Client.ListRepoBranches(owner, repo, gitea.ListRepoBranchesOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     pageIndex,
+				PageSize: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository branches: %w", err)
+		}
+		if len(repoBranches) == 0 {
+			break
+		}
+
+		for _, branch := range repoBranches {
+			repositoryBranch := &gitops.RepositoryBranch{
+				Name: branch.Name,
+			}
+			if branch.Commit != nil {
+				repositoryBranch.UpdatedAt = branch.Commit.Timestamp
+			}
+			branches = append(branches, repositoryBranch)
+		}
+	}
+
+	return branches, nil
+}
+
+func (g *Gitea) RemoveBranch(ctx context.Context, repository gitops.Repository, branch string) error {
+	owner, repo, err := g.getOwnerRepo(repository.URL)
+	if err != nil {
+		return err
+	}
+
+	deleted, _, err := g.Client.DeleteRepoBranch(owner, repo, branch)
+	if err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+	if !deleted {
+		return fmt.Errorf("failed to delete branch %s", branch)
+	}
+
+	return nil
+}
+
+// Updates the title, body and labels of an existing pull request, returning the labels which were added
+func (g *Gitea) updatePullRequest(owner, repo string, pullRequest *gitea.PullRequest, content *gitops.ReviewContent, assignment *gitops.ReviewAssignment, sendMsg func(string)) (*gitops.ReviewAssignment, error) {
+	_, _, err := g.Client.EditPullRequest(owner, repo, pullRequest.Index, gitea.EditPullRequestOption{
+		Title: content.Title,
+		Body:  content.Description,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update pull request: %w", err)
+	}
+	sendMsg("updated pull request")
+
+	if assignment != nil && len(assignment.Labels) > 0 {
+		labelIds, err := g.getLabelIds(owner, repo, assignment.Labels)
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = g.Client.AddIssueLabels(owner, repo, pullRequest.Index, gitea.IssueLabelsOption{
+			Labels: labelIds,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add labels to pull request: %w", err)
+		}
+		assignment = &gitops.ReviewAssignment{Labels: assignment.Labels}
+	} else {
+		assignment = nil
+	}
+
+	if g.CommentOnUpdate && content.UpdateComment != "" {
+		_, _, err = g.Client.CreateIssueComment(owner, repo, pullRequest.Index, gitea.CreateIssueCommentOption{
+			Body: content.UpdateComment,
+		})
+		if err != nil {
+			// The pull request is up to date, so it is not failed for want of a comment
+			slog.Warn("failed to comment on pull request", "error", err)
+			sendMsg(fmt.Sprintf("failed to comment on pull request: %s", err))
+		}
+	}
+
+	return assignment, nil
+}
+
+// Returns the approvals of a pull request and the commit statuses of its head
+func (g *Gitea) getReviewState(owner, repo string, pullRequestId int64) (*reviewState, error) {
+	pullRequest, _, err := g.Client.GetPullRequest(owner, repo, pullRequestId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request: %w", err)
+	}
+
+	// Only the latest review of each reviewer counts
+	latestReviews := make(map[int64]*gitea.PullReview)
+	for pageIndex := 1; ; pageIndex++ {
+		reviews, _, err := g.Client.ListPullReviews(owner, repo, pullRequestId, gitea.ListPullReviewsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     pageIndex,
+				PageSize: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pull request reviews: %w", err)
+		}
+		if len(reviews) == 0 {
+			break
+		}
+		for _, review := range reviews {
+			if review.Reviewer == nil || review.State == gitea.ReviewStateComment || review.State == gitea.ReviewStatePending ||
+				review.State == gitea.ReviewStateRequestReview {
+				continue
+			}
+			latestReviews[review.Reviewer.ID] = review
+		}
+	}
+
+	state := &reviewState{
+		checks: make(map[string]checkState),
+	}
+
+	state.requiredApprovals, err = g.getRequiredApprovals(owner, repo, pullRequest.Base.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, review := range latestReviews {
+		if review.State == gitea.ReviewStateApproved && !review.Stale && !review.Dismissed {
+			state.approvals++
+		}
+	}
+
+	combinedStatus, _, err := g.Client.GetCombinedStatus(owner, repo, pullRequest.Head.Sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit statuses: %w", err)
+	}
+	for _, status := range combinedStatus.Statuses {
+		switch status.State {
+		case gitea.StatusSuccess, gitea.StatusWarning:
+			state.checks[status.Context] = checkPassed
+		case gitea.StatusError, gitea.StatusFailure:
+			state.checks[status.Context] = checkFailed
+		default:
+			state.checks[status.Context] = checkPending
+		}
+	}
+
+	return state, nil
+}
+
+// Returns the approvals required by the protection rule of a branch, or 0 if it is not protected or the rules cannot be
+// read with the token
+func (g *Gitea) getRequiredApprovals(owner, repo, branch string) (int, error) {
+	protection, response, err := g.Client.GetBranchProtection(owner, repo, branch)
+	if err == nil {
+		return int(protection.RequiredApprovals), nil
+	}
+	if response != nil && response.StatusCode == http.StatusForbidden {
+		slog.Warn("not permitted to read branch protection, waiting for the configured approvals only", "owner", owner, "repo", repo, "branch", branch)
+		return 0, nil
+	}
+	if response == nil || response.StatusCode != http.StatusNotFound {
+		return 0, fmt.Errorf("failed to get branch protection of %s: %w", branch, err)
+	}
+
+	// Rules are found by name, so a branch protected by a pattern such as env/* is matched against every rule. Gitea
+	// returns all rules at once and the first which matches applies.
+	protections, response, err := g.Client.ListBranchProtections(owner, repo, gitea.ListBranchProtectionsOptions{})
+	if response != nil && response.StatusCode == http.StatusForbidden {
+		slog.Warn("not permitted to list branch protections, waiting for the configured approvals only", "owner", owner, "repo", repo, "branch", branch)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list branch protections: %w", err)
+	}
+	for _, protection := range protections {
+		matched, err := matchBranchRule(protection.RuleName, branch)
+		if err != nil {
+			slog.Warn("skipping branch protection with an invalid pattern", "rule", protection.RuleName, "error", err)
+			continue
+		}
+		if matched {
+			return int(protection.RequiredApprovals), nil
+		}
+	}
+
+	return 0, nil
+}
+
+// Reports whether a branch protection rule applies to a branch. As in Gitea, * does not match a slash and ** matches any
+// number of path segments.
+func matchBranchRule(rule, branch string) (bool, error) {
+	if !strings.Contains(rule, "/") {
+		return path.Match(rule, branch)
+	}
+
+	return util.MatchGlob(rule, branch)
+}
+
+// Returns the IDs of labels by name, creating any which do not exist in the repository
+func (g *Gitea) getLabelIds(owner, repo string, names []string) ([]int64, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	existing := make(map[string]int64)
+	for pageIndex := 1; ; pageIndex++ {
+		labels, _, err := g.Client.ListRepoLabels(owner, repo, gitea.ListLabelsOptions{
+			ListOptions: gitea.ListOptions{
+				Page:     pageIndex,
+				PageSize: 100,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repository labels: %w", err)
+		}
+		if len(labels) == 0 {
+			break
+		}
+		for _, label := range labels {
+			existing[label.Name] = label.ID
+		}
+	}
+
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := existing[name]
+		if !ok {
+			label, _, err := g.Client.CreateLabel(owner, repo, gitea.CreateLabelOption{
+				Name:  name,
+				Color: giteaLabelColor,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create label %q: %w", name, err)
+			}
+			id = label.ID
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Returns the names of the team reviewers of an assignment. Gitea only supports teams of the repository owner, so other
+// teams are removed from the assignment.
+func (g *Gitea) teamNames(owner string, assignment *gitops.ReviewAssignment, sendMsg func(string)) []string {
+	names := make([]string, 0, len(assignment.TeamReviewers))
+	assignment.TeamReviewers = slices.DeleteFunc(assignment.TeamReviewers, func(team string) bool {
+		org, name, _ := strings.Cut(team, "/")
+		if !strings.EqualFold(org, owner) {
+			sendMsg(fmt.Sprintf("skipping team reviewer %s which does not belong to %s", team, owner))
+			return true
+		}
+		names = append(names, name)
+		return false
+	})
+
+	return names
+}
+
+func (g *Gitea) getOwnerRepoAndPullRequestId(pullRequestUrl string) (string, string, int64, error) {
+	parsedUrl, err := url.Parse(pullRequestUrl)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to parse pull request URL: %w", err)
+	}
+	repositoryPath, pullRequestIndex, found := strings.Cut(parsedUrl.Path, "/pulls/")
+	if !found {
+		return "", "", 0, fmt.Errorf("failed to parse pull request URL: %s is not a pull request", pullRequestUrl)
+	}
+	owner, repo, err := g.getOwnerRepo(repositoryPath)
+	if err != nil {
+		return "", "", 0, err
+	}
+	pullRequestId, err := strconv.ParseInt(pullRequestIndex, 10, 64)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to parse ID from pull request URL: %w", err)
+	}
+
+	return owner, repo, pullRequestId, nil
+}
+
 func (g *Gitea) getOwnerRepo(repositoryUrl string) (string, string, error) {
 	targetRepositoryUrl, err := url.Parse(repositoryUrl)
 	if err != nil {
@@ -15,8 +15,11 @@ import (
 )
 
 type Gitlab struct {
-	Client       *gitlab.Client
-	MergeOptions *GitlabMergeOptions
+	Client          *gitlab.Client
+	MergeOptions    *GitlabMergeOptions
+	Assignment      *Assignment  // Labels, assignees, reviewers and a milestone for created merge requests. Labels are also added when merge requests are updated. Team reviewers are not supported.
+	Wait            *WaitOptions // If set, merge requests are merged once approved and their pipeline jobs and commit statuses pass
+	CommentOnUpdate bool         // Whether to comment on an existing merge request with a summary of the changes when it is updated
 }
 
 type GitlabMergeOptions struct {
@@ -48,40 +51,47 @@ func (g *Gitlab) CreateReview(ctx context.Context, req *gitops.Request, target *
 		mergeRequest = mergeRequests[0]
 	}
 
+	content, err := reviewContent(req, target)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment, err := g.Assignment.resolve(req, target)
+	if err != nil {
+		return nil, err
+	}
+
 	if mergeRequest != nil {
 		sendMsg("merge Request already exists")
+		assignment, err = g.updateMergeRequest(projectId, mergeRequest, content, assignment, sendMsg)
+		if err != nil {
+			return nil, err
+		}
+
 		return &gitops.CreateReviewResult{
-			Created:   true,
-			URL:       mergeRequest.WebURL,
-			Completed: mergeRequest.State == "merged",
+			Created:    true,
+			URL:        mergeRequest.WebURL,
+			Completed:  mergeRequest.State == "merged",
+			Assignment: assignment,
 		}, nil
 	}
 
-	mr, response, err := g.Client.MergeRequests.CreateMergeRequest(projectId, &gitlab.CreateMergeRequestOptions{
+	createOptions := &gitlab.CreateMergeRequestOptions{
 		TargetBranch:       gitlab.Ptr(target.Branch.Target),
 		SourceBranch:       gitlab.Ptr(target.Branch.Source),
 		Squash:             gitlab.Ptr(g.MergeOptions.Squash),
 		RemoveSourceBranch: gitlab.Ptr(g.MergeOptions.DeleteBranch),
-		Title:              gitlab.Ptr(fmt.Sprintf("Promote %s [%s] to %s", req.AppName, req.UpdateIdentifier, req.Environment)),
-		Description: gitlab.Ptr(fmt.Sprintf(`<table>
-  <tr>
-    <td><strong>Target Environment</strong></td>
-	<td>%s</td>
-  </tr>
-  <tr>
-	<td><strong>Source Repository</strong></td>
-	<td><a href="%s">%s</a></td>
-  </tr>
-  <tr>
-	<td><strong>Source Branch</strong></td>
-	<td><a href="%s/tree/%s">%s</a></td>
-  </tr>
-  <tr>
-	<td><strong>App Name</strong></td>
-	<td>%s</td>
-  </tr>
-</table>`, req.Environment, req.Source.Repository.URL, req.Source.Repository.URL, req.Source.Repository.URL, req.UpdateIdentifier, req.UpdateIdentifier, req.AppName),
-		)})
+		Title:              gitlab.Ptr(content.Title),
+		Description:        gitlab.Ptr(content.Description),
+	}
+	if assignment != nil {
+		err = g.assign(projectId, assignment, createOptions, sendMsg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mr, response, err := g.Client.MergeRequests.CreateMergeRequest(projectId, createOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create merge request: %w", err)
 	}
@@ -91,9 +101,10 @@ func (g *Gitlab) CreateReview(ctx context.Context, req *gitops.Request, target *
 	}
 
 	result := &gitops.CreateReviewResult{
-		Created:   true,
-		URL:       mr.WebURL,
-		Completed: mr.State == "merged",
+		Created:    true,
+		URL:        mr.WebURL,
+		Completed:  mr.State == "merged",
+		Assignment: assignment,
 	}
 	slog.Info("created merge request", "result", result)
 
@@ -118,6 +129,15 @@ func (g *Gitlab) CompleteReview(ctx context.Context, req *gitops.Request, create
 		acceptOptions.MergeCommitMessage = gitlab.Ptr(g.MergeOptions.CommitMessage)
 	}
 
+	if g.Wait != nil {
+		err = g.Wait.wait(ctx, sendMsg, func(ctx context.Context) (*reviewState, error) {
+			return g.getReviewState(ctx, projectId, mergeRequestId)
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
 	sendMsg("merging merge request")
 	retries := 0
 	retryLimit := 10
@@ -148,6 +168,236 @@ Merge:
 	return merged, nil
 }
 
+func (g *Gitlab) ListOpenReviews(ctx context.Context, repository gitops.Repository) ([]*gitops.OpenReview, error) {
+	projectId, err := g.getProjectId(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeRequests, err := gitlab.ScanAndCollect(func(p gitlab.PaginationOptionFunc) ([]*gitlab.BasicMergeRequest, *gitlab.Response, error) {
+		return g.Client.MergeRequests.ListProjectMergeRequests(projectId, &gitlab.ListProjectMergeRequestsOptions{
+			State: gitlab.Ptr("opened"),
+		}, p, gitlab.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository merge requests: %w", err)
+	}
+
+	reviews := make([]*gitops.OpenReview, 0, len(mergeRequests))
+	for _, mergeRequest := range mergeRequests {
+		review := &gitops.OpenReview{
+			URL:          mergeRequest.WebURL,
+			SourceBranch: mergeRequest.SourceBranch,
+			TargetBranch: mergeRequest.TargetBranch,
+		}
+		if mergeRequest.UpdatedAt != nil {
+			review.UpdatedAt = *mergeRequest.UpdatedAt
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (g *Gitlab) CloseReview(ctx context.Context, review *gitops.OpenReview, comment string) error {
+	projectId, mergeRequestId, err := g.getProjectIdAndMergeRequestId(review.URL)
+	if err != nil {
+		return err
+	}
+
+	if comment != "" {
+		_, _, err = g.Client.Notes.CreateMergeRequestNote(projectId, mergeRequestId, &gitlab.CreateMergeRequestNoteOptions{
+			Body: gitlab.Ptr(comment),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to comment on merge request: %w", err)
+		}
+	}
+
+	_, _, err = g.Client.MergeRequests.UpdateMergeRequest(projectId, mergeRequestId, &gitlab.UpdateMergeRequestOptions{
+		StateEvent: gitlab.Ptr("close"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to close merge request: %w", err)
+	}
+
+	return nil
+}
+
+func (g *Gitlab) ListBranches(ctx context.Context, repository gitops.Repository) ([]*gitops.RepositoryBranch, error) {
+	projectId, err := g.getProjectId(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	projectBranches, err := gitlab.ScanAndCollect(func(p gitlab.PaginationOptionFunc) ([]*gitlab.Branch, *gitlab.Response, error) {
+		return g.Client.Branches.ListBranches(projectId, &gitlab.ListBranchesOptions{}, p, gitlab.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository branches: %w", err)
+	}
+
+	branches := make([]*gitops.RepositoryBranch, 0, len(projectBranches))
+	for _, projectBranch := range projectBranches {
+		branch := &gitops.RepositoryBranch{
+			Name: projectBranch.Name,
+		}
+		if projectBranch.Commit != nil && projectBranch.Commit.CommittedDate != nil {
+			branch.UpdatedAt = *projectBranch.Commit.CommittedDate
+		}
+		branches = append(branches, branch)
+	}
+
+	return branches, nil
+}
+
+func (g *Gitlab) RemoveBranch(ctx context.Context, repository gitops.Repository, branch string) error {
+	projectId, err := g.getProjectId(repository.URL)
+	if err != nil {
+		return err
+	}
+
+	_, err = g.Client.Branches.DeleteBranch(projectId, branch, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// Updates the title, description and labels of an existing merge request, returning the labels which were added
+func (g *Gitlab) updateMergeRequest(projectId string, mergeRequest *gitlab.BasicMergeRequest, content *gitops.ReviewContent, assignment *gitops.ReviewAssignment, sendMsg func(string)) (*gitops.ReviewAssignment, error) {
+	updateOptions := &gitlab.UpdateMergeRequestOptions{
+		Title:       gitlab.Ptr(content.Title),
+		Description: gitlab.Ptr(content.Description),
+	}
+	if assignment != nil && len(assignment.Labels) > 0 {
+		updateOptions.AddLabels = gitlab.Ptr(gitlab.LabelOptions(assignment.Labels))
+		assignment = &gitops.ReviewAssignment{Labels: assignment.Labels}
+	} else {
+		assignment = nil
+	}
+
+	_, _, err := g.Client.MergeRequests.UpdateMergeRequest(projectId, mergeRequest.IID, updateOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update merge request: %w", err)
+	}
+	sendMsg("updated merge request")
+
+	if g.CommentOnUpdate && content.UpdateComment != "" {
+		_, _, err = g.Client.Notes.CreateMergeRequestNote(projectId, mergeRequest.IID, &gitlab.CreateMergeRequestNoteOptions{
+			Body: gitlab.Ptr(content.UpdateComment),
+		})
+		if err != nil {
+			// The merge request is up to date, so it is not failed for want of a comment
+			slog.Warn("failed to comment on merge request", "error", err)
+			sendMsg(fmt.Sprintf("failed to comment on merge request: %s", err))
+		}
+	}
+
+	return assignment, nil
+}
+
+// Sets the labels, assignees, reviewers and milestone of an assignment on the options of a new merge request. Gitlab
+// has no team reviewers, so these are removed from the assignment.
+func (g *Gitlab) assign(projectId string, assignment *gitops.ReviewAssignment, createOptions *gitlab.CreateMergeRequestOptions, sendMsg func(string)) error {
+	if len(assignment.Labels) > 0 {
+		createOptions.Labels = gitlab.Ptr(gitlab.LabelOptions(assignment.Labels))
+	}
+
+	assigneeIds, err := g.getUserIds(assignment.Assignees)
+	if err != nil {
+		return err
+	}
+	if len(assigneeIds) > 0 {
+		createOptions.AssigneeIDs = &assigneeIds
+	}
+
+	reviewerIds, err := g.getUserIds(assignment.Reviewers)
+	if err != nil {
+		return err
+	}
+	if len(reviewerIds) > 0 {
+		createOptions.ReviewerIDs = &reviewerIds
+	}
+
+	for _, team := range assignment.TeamReviewers {
+		sendMsg(fmt.Sprintf("skipping team reviewer %s which is not supported by Gitlab", team))
+	}
+	assignment.TeamReviewers = nil
+
+	if assignment.Milestone != "" {
+		milestones, _, err := g.Client.Milestones.ListMilestones(projectId, &gitlab.ListMilestonesOptions{
+			Title:            gitlab.Ptr(assignment.Milestone),
+			IncludeAncestors: gitlab.Ptr(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list milestones: %w", err)
+		}
+		if len(milestones) == 0 {
+			return fmt.Errorf("milestone %q not found", assignment.Milestone)
+		}
+		createOptions.MilestoneID = gitlab.Ptr(milestones[0].ID)
+	}
+
+	return nil
+}
+
+func (g *Gitlab) getUserIds(usernames []string) ([]int, error) {
+	ids := make([]int, 0, len(usernames))
+	for _, username := range usernames {
+		users, _, err := g.Client.Users.ListUsers(&gitlab.ListUsersOptions{
+			Username: gitlab.Ptr(username),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user %s: %w", username, err)
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+
+	return ids, nil
+}
+
+// Returns the approvals of a merge request and the commit statuses of its head, which include its pipeline jobs
+func (g *Gitlab) getReviewState(ctx context.Context, projectId string, mergeRequestId int) (*reviewState, error) {
+	mergeRequest, _, err := g.Client.MergeRequests.GetMergeRequest(projectId, mergeRequestId, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request: %w", err)
+	}
+
+	approvals, _, err := g.Client.MergeRequestApprovals.GetConfiguration(projectId, mergeRequestId, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge request approvals: %w", err)
+	}
+	state := &reviewState{
+		approvals:         len(approvals.ApprovedBy),
+		requiredApprovals: len(approvals.ApprovedBy) + approvals.ApprovalsLeft,
+		checks:            make(map[string]checkState),
+	}
+
+	statuses, err := gitlab.ScanAndCollect(func(p gitlab.PaginationOptionFunc) ([]*gitlab.CommitStatus, *gitlab.Response, error) {
+		return g.Client.Commits.GetCommitStatuses(projectId, mergeRequest.SHA, &gitlab.GetCommitStatusesOptions{}, p, gitlab.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit statuses: %w", err)
+	}
+	for _, status := range statuses {
+		switch {
+		case status.Status == "success" || status.Status == "skipped" || status.AllowFailure:
+			state.checks[status.Name] = checkPassed
+		case status.Status == "failed" || status.Status == "canceled":
+			state.checks[status.Name] = checkFailed
+		default:
+			state.checks[status.Name] = checkPending
+		}
+	}
+
+	return state, nil
+}
+
 func (g *Gitlab) getProjectId(repositoryUrl string) (string, error) {
 	targetRepositoryUrl, err := url.Parse(repositoryUrl)
 	if err != nil {
@@ -0,0 +1,423 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	igit "github.com/tvandinther/gitops-manager/internal/git"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	pgit "github.com/tvandinther/gitops-manager/pkg/gitops/git"
+)
+
+type LocalMergeStrategy string
+
+const (
+	LocalMergeFastForward LocalMergeStrategy = "ff"
+	LocalMergeCommit      LocalMergeStrategy = "merge"
+	LocalMergeSquash      LocalMergeStrategy = "squash"
+)
+
+// This reviewer records reviews as JSON files in a directory and completes them by merging the branches of a
+// configuration repository on the local file system, such as a bare repository used for testing or in an air-gapped
+// environment. No forge is required.
+type Local struct {
+	Directory     string             // The directory in which reviews are recorded
+	MergeStrategy LocalMergeStrategy // Defaults to LocalMergeFastForward
+	Author        *pgit.Author       // The author of merge and squash commits
+	CommitMessage string             // The message of merge and squash commits. Defaults to the review title.
+	DeleteBranch  bool               // Whether to delete the source branch once merged
+
+	mu sync.Mutex
+}
+
+// A review recorded by the Local reviewer
+type LocalReview struct {
+	ID               int              `json:"id"`
+	Title            string           `json:"title"`
+	Description      string           `json:"description"`
+	Repository       string           `json:"repository"`
+	SourceBranch     string           `json:"sourceBranch"`
+	TargetBranch     string           `json:"targetBranch"`
+	Environment      string           `json:"environment"`
+	AppName          string           `json:"appName"`
+	UpdateIdentifier string           `json:"updateIdentifier"`
+	Findings         []gitops.Finding `json:"findings"`
+	Status           string           `json:"status"`
+	CreatedAt        time.Time        `json:"createdAt"`
+	MergedAt         *time.Time       `json:"mergedAt,omitempty"`
+	MergeCommit      string           `json:"mergeCommit,omitempty"`
+	ClosedAt         *time.Time       `json:"closedAt,omitempty"`
+	CloseComment     string           `json:"closeComment,omitempty"`
+}
+
+const (
+	localReviewOpen   = "open"
+	localReviewMerged = "merged"
+	localReviewClosed = "closed"
+)
+
+func (l *Local) CreateReview(ctx context.Context, req *gitops.Request, target *gitops.Target, sendMsg func(string)) (*gitops.CreateReviewResult, error) {
+	repositoryPath, err := localRepositoryPath(target.Repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err = os.MkdirAll(l.Directory, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review directory: %w", err)
+	}
+	reviews, err := l.reviews()
+	if err != nil {
+		return nil, err
+	}
+
+	lastId := 0
+	for _, review := range reviews {
+		lastId = max(lastId, review.ID)
+		if review.Status == localReviewOpen && review.Repository == repositoryPath && review.SourceBranch == target.Branch.Source && review.TargetBranch == target.Branch.Target {
+			sendMsg("review already exists")
+			return &gitops.CreateReviewResult{
+				Created:   true,
+				URL:       l.reviewURL(review.ID),
+				Completed: false,
+			}, nil
+		}
+	}
+
+	content, err := reviewContent(req, target)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &LocalReview{
+		ID:               lastId + 1,
+		Title:            content.Title,
+		Description:      content.Description,
+		Repository:       repositoryPath,
+		SourceBranch:     target.Branch.Source,
+		TargetBranch:     target.Branch.Target,
+		Environment:      req.Environment,
+		AppName:          req.AppName,
+		UpdateIdentifier: req.UpdateIdentifier,
+		Findings:         req.Findings,
+		Status:           localReviewOpen,
+		CreatedAt:        time.Now().UTC(),
+	}
+	err = l.writeReview(review)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &gitops.CreateReviewResult{
+		Created:   true,
+		URL:       l.reviewURL(review.ID),
+		Completed: false,
+	}
+	slog.Info("created review", "result", result)
+
+	return result, nil
+}
+
+func (l *Local) CompleteReview(ctx context.Context, req *gitops.Request, createReviewResult *gitops.CreateReviewResult, sendMsg func(string)) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	review, err := l.readReview(createReviewResult.URL)
+	if err != nil {
+		return false, err
+	}
+	if review.Status == localReviewMerged {
+		sendMsg("review already merged")
+		return true, nil
+	}
+
+	repo, err := git.PlainOpen(review.Repository)
+	if err != nil {
+		return false, fmt.Errorf("failed to open repository %s: %w", review.Repository, err)
+	}
+
+	into := plumbing.NewBranchReferenceName(review.TargetBranch)
+	from := plumbing.NewBranchReferenceName(review.SourceBranch)
+	message := l.CommitMessage
+	if message == "" {
+		message = review.Title
+	}
+
+	sourceRef, err := repo.Reference(from, true)
+	if err != nil {
+		return false, fmt.Errorf("failed to get source branch: %w", err)
+	}
+
+	sendMsg(fmt.Sprintf("merging %s into %s", review.SourceBranch, review.TargetBranch))
+	var mergeCommit plumbing.Hash
+	switch l.MergeStrategy {
+	case "", LocalMergeFastForward:
+		mergeCommit, err = igit.FastForward(repo, into, from)
+	case LocalMergeCommit, LocalMergeSquash:
+		if l.Author == nil {
+			return false, fmt.Errorf("an author is required for the %s merge strategy", l.MergeStrategy)
+		}
+		mergeCommit, err = igit.Merge(repo, into, from, l.Author, message, l.MergeStrategy == LocalMergeSquash)
+	default:
+		return false, fmt.Errorf("unknown merge strategy %q", l.MergeStrategy)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to merge: %w", err)
+	}
+	sendMsg(fmt.Sprintf("merged as %s", mergeCommit))
+
+	if l.DeleteBranch {
+		err = repo.Storer.RemoveReference(from)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete source branch: %w", err)
+		}
+	} else if l.MergeStrategy == LocalMergeSquash {
+		// A squash commit does not have the source branch as a parent, so the source branch is moved onto it. Otherwise
+		// later updates of the same branch would conflict with the changes they were squashed into.
+		err = igit.ResetBranch(repo, from, sourceRef.Hash(), mergeCommit)
+		if err != nil {
+			return false, fmt.Errorf("failed to move source branch onto the squash commit: %w", err)
+		}
+	}
+
+	mergedAt := time.Now().UTC()
+	review.Status = localReviewMerged
+	review.MergedAt = &mergedAt
+	review.MergeCommit = mergeCommit.String()
+	err = l.writeReview(review)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (l *Local) ListOpenReviews(ctx context.Context, repository gitops.Repository) ([]*gitops.OpenReview, error) {
+	repositoryPath, err := localRepositoryPath(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	reviews, err := l.reviews()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	openReviews := make([]*gitops.OpenReview, 0)
+	for _, review := range reviews {
+		if review.Status != localReviewOpen || review.Repository != repositoryPath {
+			continue
+		}
+		openReviews = append(openReviews, &gitops.OpenReview{
+			URL:          l.reviewURL(review.ID),
+			SourceBranch: review.SourceBranch,
+			TargetBranch: review.TargetBranch,
+			UpdatedAt:    review.CreatedAt,
+		})
+	}
+
+	return openReviews, nil
+}
+
+func (l *Local) CloseReview(ctx context.Context, openReview *gitops.OpenReview, comment string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	review, err := l.readReview(openReview.URL)
+	if err != nil {
+		return err
+	}
+	if review.Status != localReviewOpen {
+		return fmt.Errorf("review %d is %s", review.ID, review.Status)
+	}
+
+	closedAt := time.Now().UTC()
+	review.Status = localReviewClosed
+	review.ClosedAt = &closedAt
+	review.CloseComment = comment
+
+	return l.writeReview(review)
+}
+
+func (l *Local) ListBranches(ctx context.Context, repository gitops.Repository) ([]*gitops.RepositoryBranch, error) {
+	repo, err := l.openRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.Branches()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer refs.Close()
+
+	branches := make([]*gitops.RepositoryBranch, 0)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get commit of %s: %w", ref.Name().Short(), err)
+		}
+		branches = append(branches, &gitops.RepositoryBranch{
+			Name:      ref.Name().Short(),
+			UpdatedAt: commit.Committer.When,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return branches, nil
+}
+
+func (l *Local) RemoveBranch(ctx context.Context, repository gitops.Repository, branch string) error {
+	repo, err := l.openRepository(repository)
+	if err != nil {
+		return err
+	}
+
+	err = repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(branch))
+	if err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+func (l *Local) openRepository(repository gitops.Repository) (*git.Repository, error) {
+	repositoryPath, err := localRepositoryPath(repository.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpen(repositoryPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %s: %w", repositoryPath, err)
+	}
+
+	return repo, nil
+}
+
+func (l *Local) reviews() ([]*LocalReview, error) {
+	entries, err := os.ReadDir(l.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reviews: %w", err)
+	}
+
+	reviews := make([]*LocalReview, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		_, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		review, err := l.readReviewFile(filepath.Join(l.Directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, nil
+}
+
+func (l *Local) readReview(reviewUrl string) (*LocalReview, error) {
+	parsedUrl, err := url.Parse(reviewUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse review URL: %w", err)
+	}
+	if parsedUrl.Scheme != "file" {
+		return nil, fmt.Errorf("review URL %s is not a file URL", reviewUrl)
+	}
+
+	return l.readReviewFile(parsedUrl.Path)
+}
+
+func (l *Local) readReviewFile(path string) (*LocalReview, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read review: %w", err)
+	}
+
+	review := &LocalReview{}
+	err = json.Unmarshal(content, review)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse review %s: %w", path, err)
+	}
+
+	return review, nil
+}
+
+// Writes a review through a temporary file so that readers never see a partial review
+func (l *Local) writeReview(review *LocalReview) error {
+	content, err := json.MarshalIndent(review, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode review: %w", err)
+	}
+
+	path := l.reviewPath(review.ID)
+	err = os.WriteFile(path+".tmp", append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
+	err = os.Rename(path+".tmp", path)
+	if err != nil {
+		return fmt.Errorf("failed to write review: %w", err)
+	}
+
+	return nil
+}
+
+func (l *Local) reviewPath(id int) string {
+	return filepath.Join(l.Directory, strconv.Itoa(id)+".json")
+}
+
+func (l *Local) reviewURL(id int) string {
+	path, err := filepath.Abs(l.reviewPath(id))
+	if err != nil {
+		path = l.reviewPath(id)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// Returns the file system path of a repository given as a path or a file URL
+func localRepositoryPath(repositoryUrl string) (string, error) {
+	parsedUrl, err := url.Parse(repositoryUrl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse target repository URL: %w", err)
+	}
+
+	switch parsedUrl.Scheme {
+	case "":
+		return filepath.Abs(repositoryUrl)
+	case "file":
+		return filepath.Abs(filepath.FromSlash(parsedUrl.Path))
+	default:
+		return "", errors.New("the local reviewer requires a repository on the local file system")
+	}
+}
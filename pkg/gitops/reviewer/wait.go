@@ -0,0 +1,163 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Waits for a review to be approved and for the checks of its source branch to pass before it is completed
+type WaitOptions struct {
+	RequiredApprovals int           // The number of approvals to wait for, in addition to any approval rules of the forge
+	RequiredChecks    []string      // Checks which must be reported and pass. Any other reported checks must pass too.
+	Interval          time.Duration // How often to poll the review. Defaults to 10 seconds.
+	Timeout           time.Duration // How long to wait before failing. Defaults to 30 minutes.
+}
+
+// Returned when a review is not ready to be completed, either because a check failed or the wait timed out
+type WaitError struct {
+	Reason            string
+	Approvals         int
+	RequiredApprovals int
+	Pending           []string // Checks which are pending or not yet reported
+	Failed            []string // Checks which failed
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, (&reviewStatus{
+		approvals:         e.Approvals,
+		requiredApprovals: e.RequiredApprovals,
+		pending:           e.Pending,
+		failed:            e.Failed,
+	}).String())
+}
+
+type checkState int
+
+const (
+	checkPending checkState = iota
+	checkPassed
+	checkFailed
+)
+
+// The approvals and checks of a review as reported by a forge
+type reviewState struct {
+	approvals         int
+	requiredApprovals int                   // The approvals required by the forge
+	checks            map[string]checkState // The latest state of each check by name
+}
+
+type reviewStatus struct {
+	approvals         int
+	requiredApprovals int
+	pending           []string
+	failed            []string
+}
+
+func (s *reviewStatus) ready() bool {
+	return s.approvals >= s.requiredApprovals && len(s.pending) == 0 && len(s.failed) == 0
+}
+
+// Returns a summary such as "1 of 2 approvals; pending checks: build; failed checks: lint"
+func (s *reviewStatus) String() string {
+	parts := []string{fmt.Sprintf("%d of %d approvals", s.approvals, s.requiredApprovals)}
+	if len(s.pending) > 0 {
+		parts = append(parts, "pending checks: "+strings.Join(s.pending, ", "))
+	}
+	if len(s.failed) > 0 {
+		parts = append(parts, "failed checks: "+strings.Join(s.failed, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func (s *reviewStatus) error(reason string) *WaitError {
+	return &WaitError{
+		Reason:            reason,
+		Approvals:         s.approvals,
+		RequiredApprovals: s.requiredApprovals,
+		Pending:           s.pending,
+		Failed:            s.failed,
+	}
+}
+
+func (w *WaitOptions) status(state *reviewState) *reviewStatus {
+	status := &reviewStatus{
+		approvals:         state.approvals,
+		requiredApprovals: max(w.RequiredApprovals, state.requiredApprovals),
+		pending:           make([]string, 0),
+		failed:            make([]string, 0),
+	}
+	for _, name := range slices.Sorted(maps.Keys(state.checks)) {
+		switch state.checks[name] {
+		case checkPending:
+			status.pending = append(status.pending, name)
+		case checkFailed:
+			status.failed = append(status.failed, name)
+		}
+	}
+	for _, name := range w.RequiredChecks {
+		if _, reported := state.checks[name]; !reported {
+			status.pending = append(status.pending, name)
+		}
+	}
+
+	return status
+}
+
+// Polls the state of a review until it is ready to be completed, sending each change in status. A failed check fails
+// immediately, as does cancellation of the context.
+func (w *WaitOptions) wait(ctx context.Context, sendMsg func(string), poll func(ctx context.Context) (*reviewState, error)) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sendMsg("waiting for approvals and checks")
+	status := w.status(&reviewState{})
+	lastReport := ""
+	for {
+		state, err := poll(waitCtx)
+		if err != nil {
+			if waitCtx.Err() == nil {
+				return err
+			}
+		} else {
+			status = w.status(state)
+		}
+
+		report := status.String()
+		if report != lastReport {
+			sendMsg(report)
+			lastReport = report
+		}
+		if len(status.failed) > 0 {
+			return status.error("checks failed")
+		}
+		if status.ready() {
+			return nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-waitCtx.Done():
+			timer.Stop()
+			if ctx.Err() != nil {
+				slog.Info("stopped waiting for review", "status", report)
+				return fmt.Errorf("stopped waiting for the review: %w", ctx.Err())
+			}
+			return status.error(fmt.Sprintf("timed out after %s waiting for the review", timeout))
+		case <-timer.C:
+		}
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
 )
 
 type ServiceOptions struct {
@@ -263,6 +265,126 @@ func (s *Service) Push(configRepository Repository) error {
 	return nil
 }
 
+// Compares the next branch with the trunk branch
+func (s *Service) DiffStats() (*DiffStats, error) {
+	environmentBranches := s.environmentConfig.branches
+
+	trunkTree, err := s.branchTree(environmentBranches.Trunk)
+	if err != nil {
+		return nil, err
+	}
+	nextTree, err := s.branchTree(environmentBranches.Next)
+	if err != nil {
+		return nil, err
+	}
+
+	diffStats, err := diffTrees(trunkTree, nextTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", environmentBranches.Next.Short(), environmentBranches.Trunk.Short(), err)
+	}
+
+	return diffStats, nil
+}
+
+// Compares the head of the next branch with its parent, which is the head before the last commit
+func (s *Service) CommitDiffStats() (*DiffStats, error) {
+	next := s.environmentConfig.branches.Next
+
+	ref, err := s.repository.Reference(next, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference %s: %w", next, err)
+	}
+	commit, err := s.repository.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit of %s: %w", next.Short(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", next.Short(), err)
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent of %s: %w", commit.Hash, err)
+		}
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree of %s: %w", parent.Hash, err)
+		}
+	}
+
+	diffStats, err := diffTrees(parentTree, tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with its parent: %w", commit.Hash, err)
+	}
+
+	return diffStats, nil
+}
+
+// Summarises the changes between two trees, either of which may be nil
+func diffTrees(from, to *object.Tree) (*DiffStats, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diffStats := &DiffStats{
+		Files: make([]FileDiffStats, 0, len(changes)),
+	}
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get change action: %w", err)
+		}
+		patch, err := change.Patch()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get change patch: %w", err)
+		}
+
+		fileDiffStats := FileDiffStats{
+			Path: change.To.Name,
+		}
+		switch action {
+		case merkletrie.Insert:
+			fileDiffStats.Status = "added"
+		case merkletrie.Delete:
+			fileDiffStats.Status = "deleted"
+			fileDiffStats.Path = change.From.Name
+		default:
+			fileDiffStats.Status = "modified"
+		}
+		for _, fileStat := range patch.Stats() {
+			fileDiffStats.Additions += fileStat.Addition
+			fileDiffStats.Deletions += fileStat.Deletion
+		}
+
+		diffStats.Files = append(diffStats.Files, fileDiffStats)
+		diffStats.Additions += fileDiffStats.Additions
+		diffStats.Deletions += fileDiffStats.Deletions
+	}
+
+	return diffStats, nil
+}
+
+func (s *Service) branchTree(branch plumbing.ReferenceName) (*object.Tree, error) {
+	ref, err := s.repository.Reference(branch, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reference %s: %w", branch, err)
+	}
+	commit, err := s.repository.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit of %s: %w", branch.Short(), err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree of %s: %w", branch.Short(), err)
+	}
+
+	return tree, nil
+}
+
 func safeRemoveDir(wt *git.Worktree, dir string) error {
 	entries, err := os.ReadDir(path.Join(wt.Filesystem.Root(), dir))
 	if err != nil {
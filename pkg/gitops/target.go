@@ -15,3 +15,16 @@ type TargetBranch struct {
 type Targeter interface {
 	CreateTarget(req *Request) (*Target, error)
 }
+
+// The request which a source branch was created for
+type SourceBranch struct {
+	Environment      string
+	AppName          string
+	UpdateIdentifier string
+}
+
+// Implemented by targeters which can identify the source branches of the targets they create, so that stale branches
+// can be pruned
+type SourceBranchParser interface {
+	ParseSourceBranch(branch string) (*SourceBranch, bool)
+}
@@ -2,6 +2,7 @@ package targeters
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/tvandinther/gitops-manager/pkg/gitops"
 )
@@ -60,3 +61,24 @@ func (b *Branch) CreateTarget(req *gitops.Request) (*gitops.Target, error) {
 
 	return target, nil
 }
+
+func (b *Branch) ParseSourceBranch(branch string) (*gitops.SourceBranch, bool) {
+	branch, found := strings.CutPrefix(branch, b.Prefix)
+	if !found {
+		return nil, false
+	}
+	environment, rest, found := strings.Cut(branch, "-next/")
+	if !found {
+		return nil, false
+	}
+	appName, updateIdentifier, found := strings.Cut(rest, "/")
+	if !found || environment == "" || appName == "" || updateIdentifier == "" {
+		return nil, false
+	}
+
+	return &gitops.SourceBranch{
+		Environment:      environment,
+		AppName:          appName,
+		UpdateIdentifier: updateIdentifier,
+	}, true
+}
@@ -30,3 +30,7 @@ func (d *Directory) CreateTarget(req *gitops.Request) (*gitops.Target, error) {
 
 	return target, nil
 }
+
+func (d *Directory) ParseSourceBranch(branch string) (*gitops.SourceBranch, bool) {
+	return parseNextBranch(branch)
+}
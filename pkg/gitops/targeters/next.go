@@ -0,0 +1,21 @@
+package targeters
+
+import (
+	"strings"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// Parses a source branch of the form next/<environment>/<app name>/<update identifier>
+func parseNextBranch(branch string) (*gitops.SourceBranch, bool) {
+	parts := strings.SplitN(branch, "/", 4)
+	if len(parts) != 4 || parts[0] != "next" || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return nil, false
+	}
+
+	return &gitops.SourceBranch{
+		Environment:      parts[1],
+		AppName:          parts[2],
+		UpdateIdentifier: parts[3],
+	}, true
+}
@@ -32,3 +32,7 @@ func (r *Repository) CreateTarget(req *gitops.Request) (*gitops.Target, error) {
 
 	return target, nil
 }
+
+func (r *Repository) ParseSourceBranch(branch string) (*gitops.SourceBranch, bool) {
+	return parseNextBranch(branch)
+}
@@ -0,0 +1,69 @@
+package targeters
+
+import (
+	"testing"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// A targeter which can both create targets and recognise their source branches
+type sourceBranchTargeter interface {
+	gitops.Targeter
+	gitops.SourceBranchParser
+}
+
+func TestParseSourceBranchOfCreatedTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		targeter sourceBranchTargeter
+		foreign  []string // Branches which the targeter did not create
+	}{
+		{
+			name:     "branch",
+			targeter: &Branch{Prefix: "env/", Orphan: true},
+			foreign:  []string{"env/dev", "dev-next/app/1", "env/dev-next/app", "env/-next/app/1", "next/dev/app/1"},
+		},
+		{
+			name:     "directory",
+			targeter: &Directory{Branch: "main"},
+			foreign:  []string{"main", "next/dev/app", "next//app/1", "feature/dev/app/1"},
+		},
+		{
+			name: "repository",
+			targeter: &Repository{
+				MapRepositoryFn: func(environment string) (gitops.Repository, error) {
+					return gitops.Repository{URL: "https://example.com/" + environment + ".git"}, nil
+				},
+				Branch: "main",
+			},
+			foreign: []string{"main", "next/dev", "next/dev/app/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := gitops.SourceBranch{Environment: "dev", AppName: "app", UpdateIdentifier: "feature/foo"}
+			target, err := tt.targeter.CreateTarget(&gitops.Request{
+				Environment:      want.Environment,
+				AppName:          want.AppName,
+				UpdateIdentifier: want.UpdateIdentifier,
+			})
+			if err != nil {
+				t.Fatalf("CreateTarget() error = %s", err)
+			}
+
+			source, ok := tt.targeter.ParseSourceBranch(target.Branch.Source)
+			if !ok {
+				t.Fatalf("ParseSourceBranch(%q) did not recognise the source branch", target.Branch.Source)
+			}
+			if *source != want {
+				t.Errorf("ParseSourceBranch(%q) = %+v, want %+v", target.Branch.Source, *source, want)
+			}
+
+			for _, branch := range tt.foreign {
+				if source, ok := tt.targeter.ParseSourceBranch(branch); ok {
+					t.Errorf("ParseSourceBranch(%q) = %+v, want the branch to be ignored", branch, *source)
+				}
+			}
+		})
+	}
+}
@@ -15,6 +15,11 @@ type Request struct {
 	Source           *RequestSource
 	TotalFiles       int
 	Metadata         map[string]any
+	Findings         []Finding      // Non-blocking validation findings, populated by the manager after validation
+	Diff             *DiffStats     // The changes to the next branch, populated by the manager before a review is created if they can be compared
+	CommitDiff       *DiffStats     // The changes of the commit pushed to the next branch, populated alongside Diff
+	Review           *ReviewContent // The review title and description, populated by the manager before a review is created
+	Target           *Target        // The target of the request, populated by the manager before the manifests are processed
 }
 
 type RequestSource struct {
@@ -41,6 +46,7 @@ type Response struct {
 	Environment       *EnvironmentResponse `json:"environment"`
 	UpdatedFilesCount int                  `json:"updatedFilesCount"`
 	DryRun            bool                 `json:"dryRun"`
+	Findings          []Finding            `json:"findings"`
 }
 
 type EnvironmentResponse struct {
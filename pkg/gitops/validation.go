@@ -2,15 +2,74 @@ package gitops
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"io/fs"
 )
 
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "unknown"
+	}
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	switch s {
+	case "error", "":
+		return SeverityError, nil
+	case "warning":
+		return SeverityWarning, nil
+	case "info":
+		return SeverityInfo, nil
+	default:
+		return SeverityError, fmt.Errorf("unknown severity %q", s)
+	}
+}
+
+// A Finding is a validation outcome with a severity. Only findings with SeverityError will fail a request.
+type Finding struct {
+	Severity  Severity
+	Message   string
+	Path      string // Populated by the manager with the file path relative to the manifests directory if left empty
+	Validator string // Populated by the manager with the title of the validator
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Path, f.Message)
+}
+
 type ValidationResult struct {
-	IsValid bool
-	Errors  []error
+	IsValid  bool
+	Errors   []error
+	Findings []Finding
 }
 
 type Validator interface {
 	GetTitle() string
-	ValidateFile(ctx context.Context, file io.Reader, sendMsg func(string)) (*ValidationResult, error)
+	ValidateFile(ctx context.Context, request *Request, fileContext *FileContext, file io.Reader, sendMsg func(string)) (*ValidationResult, error)
+}
+
+// A SetValidator receives the full set of mutated manifests at once. Paths within files are relative to the manifests directory.
+type SetValidator interface {
+	GetTitle() string
+	ValidateFiles(ctx context.Context, request *Request, files fs.FS, sendMsg func(string)) (*ValidationResult, error)
 }
@@ -0,0 +1,190 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/google/cel-go/cel"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This validator evaluates declarative rules written as CEL expressions against each Kubernetes object
+type CELRules struct {
+	rules []*compiledCELRule
+}
+
+type CELRulesFile struct {
+	Rules []CELRule `yaml:"rules"`
+}
+
+type CELRule struct {
+	Name       string   `yaml:"name"`
+	Kinds      []string `yaml:"kinds"`      // The kinds of object the rule applies to. Omit to apply to all objects.
+	Expression string   `yaml:"expression"` // A CEL expression which must evaluate to true for the object to be valid
+	Message    string   `yaml:"message"`
+	Severity   string   `yaml:"severity"` // One of "error", "warning" or "info". Defaults to "error".
+}
+
+type compiledCELRule struct {
+	CELRule
+	severity gitops.Severity
+	program  cel.Program
+}
+
+// Loads and compiles rules from a YAML file with a top-level "rules" list
+func LoadCELRules(filename string) (*CELRules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var file CELRulesFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", filename, err)
+	}
+
+	return NewCELRules(file.Rules...)
+}
+
+// Compiles the given rules. Expressions have access to the object under validation as "object" and the request as "request".
+func NewCELRules(rules ...CELRule) (*CELRules, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+
+	compiled := make([]*compiledCELRule, 0, len(rules))
+	for _, rule := range rules {
+		severity, err := gitops.ParseSeverity(rule.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %w", rule.Name, err)
+		}
+
+		ast, issues := env.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("failed to compile rule %s: %w", rule.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+			return nil, fmt.Errorf("invalid rule %s: expression must evaluate to a bool, got %s", rule.Name, ast.OutputType())
+		}
+
+		program, err := env.Program(ast, cel.InterruptCheckFrequency(100))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create program for rule %s: %w", rule.Name, err)
+		}
+
+		compiled = append(compiled, &compiledCELRule{
+			CELRule:  rule,
+			severity: severity,
+			program:  program,
+		})
+	}
+
+	return &CELRules{rules: compiled}, nil
+}
+
+func (c *CELRules) GetTitle() string {
+	return "CEL Rules"
+}
+
+func (c *CELRules) ValidateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	result := &gitops.ValidationResult{
+		IsValid:  true,
+		Errors:   make([]error, 0),
+		Findings: make([]gitops.Finding, 0),
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(file)
+	if err != nil {
+		// Files which are not YAML are not of interest to this validator
+		return result, nil
+	}
+
+	requestVariable := celRequestVariable(request)
+
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		obj := doc.Content[0]
+		kind := yamlUtil.GetString(obj, "kind")
+		resource := yamlUtil.ResourceID(obj)
+
+		var objectVariable map[string]any
+		err := obj.Decode(&objectVariable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", resource, err)
+		}
+
+		for _, rule := range c.rules {
+			if len(rule.Kinds) > 0 && !slices.Contains(rule.Kinds, kind) {
+				continue
+			}
+
+			out, _, err := rule.program.ContextEval(ctx, map[string]any{
+				"object":  objectVariable,
+				"request": requestVariable,
+			})
+			if err != nil {
+				result.Findings = append(result.Findings, gitops.Finding{
+					Severity: rule.severity,
+					Message:  fmt.Sprintf("%s: failed to evaluate rule %s: %s", resource, rule.Name, err),
+				})
+				continue
+			}
+
+			if passed, ok := out.Value().(bool); !ok || !passed {
+				result.Findings = append(result.Findings, gitops.Finding{
+					Severity: rule.severity,
+					Message:  fmt.Sprintf("%s: %s (%s)", resource, rule.Message, rule.Name),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func celRequestVariable(req *gitops.Request) map[string]any {
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	source := map[string]any{
+		"repository": "",
+		"commitSha":  "",
+		"actor":      "",
+		"attributes": map[string]any{},
+	}
+	if req.Source != nil {
+		if req.Source.Repository != nil {
+			source["repository"] = req.Source.Repository.URL
+		}
+		if req.Source.Metadata != nil {
+			source["commitSha"] = req.Source.Metadata.CommitSHA
+			source["actor"] = req.Source.Metadata.Actor
+			if req.Source.Metadata.Attributes != nil {
+				source["attributes"] = req.Source.Metadata.Attributes
+			}
+		}
+	}
+
+	return map[string]any{
+		"environment":      req.Environment,
+		"appName":          req.AppName,
+		"updateIdentifier": req.UpdateIdentifier,
+		"dryRun":           req.DryRun,
+		"metadata":         metadata,
+		"source":           source,
+	}
+}
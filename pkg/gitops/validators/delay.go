@@ -16,7 +16,7 @@ func (d *Delay) GetTitle() string {
 	return "Delay"
 }
 
-func (d *Delay) ValidateFile(ctx context.Context, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+func (d *Delay) ValidateFile(ctx context.Context, _ *gitops.Request, _ *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
 	time.Sleep(d.Duration)
 
 	result := &gitops.ValidationResult{
@@ -0,0 +1,103 @@
+package validators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	"gopkg.in/yaml.v3"
+)
+
+// This validator checks that no Kubernetes resource is defined more than once across all manifests
+type DuplicateResources struct{}
+
+type resourceIdentity struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+func (r *resourceIdentity) key() string {
+	group := ""
+	if i := strings.LastIndex(r.APIVersion, "/"); i >= 0 {
+		group = r.APIVersion[:i]
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s", group, r.Kind, r.Metadata.Namespace, r.Metadata.Name)
+}
+
+func (d *DuplicateResources) GetTitle() string {
+	return "Duplicate Resources"
+}
+
+func (d *DuplicateResources) ValidateFiles(ctx context.Context, _ *gitops.Request, files fs.FS, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	result := &gitops.ValidationResult{
+		IsValid: true,
+		Errors:  make([]error, 0),
+	}
+
+	locations := make(map[string][]string)
+
+	err := fs.WalkDir(files, ".", func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		file, err := files.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer file.Close()
+
+		decoder := yaml.NewDecoder(file)
+		for {
+			var resource resourceIdentity
+			err := decoder.Decode(&resource)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				// Files which are not Kubernetes manifests are not of interest to this validator
+				return nil
+			}
+			if resource.Kind == "" || resource.Metadata.Name == "" {
+				continue
+			}
+
+			key := resource.key()
+			locations[key] = append(locations[key], path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests: %w", err)
+	}
+
+	keys := make([]string, 0, len(locations))
+	for key := range locations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		paths := locations[key]
+		if len(paths) > 1 {
+			result.IsValid = false
+			result.Errors = append(result.Errors, fmt.Errorf("%s is defined %d times: %s", key, len(paths), strings.Join(paths, ", ")))
+		}
+	}
+
+	return result, nil
+}
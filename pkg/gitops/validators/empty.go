@@ -14,7 +14,7 @@ func (e *EmptyFile) GetTitle() string {
 	return "Empty File"
 }
 
-func (e *EmptyFile) ValidateFile(ctx context.Context, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+func (e *EmptyFile) ValidateFile(ctx context.Context, _ *gitops.Request, _ *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
 	result := &gitops.ValidationResult{
 		IsValid: false,
 		Errors:  make([]error, 0),
@@ -0,0 +1,102 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This validator enforces a policy on the container images of every workload
+type ImagePolicy struct {
+	AllowedRegistries         []string // Glob patterns of registries images may be pulled from, e.g. "registry.example.com". Omit to allow all.
+	DeniedImages              []string // Glob patterns of fully qualified image names which may not be used, e.g. "docker.io/library/*". Patterns without a slash match the last path segment.
+	AllowLatest               bool     // Whether images may use the latest tag or omit the tag altogether
+	RequireDigestEnvironments []string // Environments in which every image must be pinned by digest
+}
+
+func (p *ImagePolicy) GetTitle() string {
+	return "Image Policy"
+}
+
+func (p *ImagePolicy) ValidateFile(ctx context.Context, request *gitops.Request, _ *gitops.FileContext, file io.Reader, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	result := &gitops.ValidationResult{
+		IsValid: true,
+		Errors:  make([]error, 0),
+	}
+
+	documents, err := yamlUtil.DecodeDocuments(file)
+	if err != nil {
+		// Files which are not YAML are not of interest to this validator
+		return result, nil
+	}
+
+	requireDigest := slices.Contains(p.RequireDigestEnvironments, request.Environment)
+
+	for _, doc := range documents {
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			continue
+		}
+		obj := doc.Content[0]
+		resource := yamlUtil.ResourceID(obj)
+
+		for _, podSpec := range yamlUtil.PodSpecs(obj) {
+			for _, container := range yamlUtil.Containers(podSpec) {
+				name := yamlUtil.GetString(container, "name")
+				image := yamlUtil.GetString(container, "image")
+
+				violations, err := p.check(image, requireDigest)
+				if err != nil {
+					return nil, err
+				}
+				for _, violation := range violations {
+					result.IsValid = false
+					result.Errors = append(result.Errors, fmt.Errorf("container %q in %s: image %q %s", name, resource, image, violation))
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (p *ImagePolicy) check(image string, requireDigest bool) ([]string, error) {
+	if image == "" {
+		return []string{"is empty"}, nil
+	}
+
+	violations := make([]string, 0)
+	ref := yamlUtil.ParseImageReference(image)
+
+	if len(p.AllowedRegistries) > 0 {
+		allowed, err := matchesAnyGlob(p.AllowedRegistries, ref.Registry)
+		if err != nil {
+			return nil, fmt.Errorf("allowed registries: %w", err)
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("is not from an allowed registry (%s)", ref.Registry))
+		}
+	}
+
+	denied, err := matchesAnyGlob(p.DeniedImages, ref.Name())
+	if err != nil {
+		return nil, fmt.Errorf("denied images: %w", err)
+	}
+	if denied {
+		violations = append(violations, "is denied")
+	}
+
+	if !p.AllowLatest && ref.Digest == "" && (ref.Tag == "" || ref.Tag == "latest") {
+		violations = append(violations, "uses the latest tag")
+	}
+
+	if requireDigest && ref.Digest == "" {
+		violations = append(violations, "is not pinned by digest")
+	}
+
+	return violations, nil
+}
@@ -0,0 +1,249 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+	yamlUtil "github.com/tvandinther/gitops-manager/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// This validator detects secrets committed in plain text. Findings never include the detected value.
+type SecretLeak struct {
+	AllowPaths       []string        // Glob patterns of manifest paths to skip, e.g. "charts/**/secrets.yaml"
+	AllowResources   []string        // Glob patterns of resources to skip in the form Kind/namespace/name, e.g. "Secret/monitoring/*"
+	Severity         gitops.Severity // The severity of findings. Defaults to gitops.SeverityError.
+	EntropyThreshold float64         // The Shannon entropy in bits per character above which a value is considered a secret. Defaults to 4.5.
+	MinLength        int             // The minimum length of a value to be checked for entropy. Defaults to 20.
+}
+
+var secretPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key ID", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{"Stripe key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}`)},
+	{"URL with credentials", regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.\-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+var secretKeyPattern = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[_\-]?key|credential|private[_\-]?key|access[_\-]?key)`)
+
+// Keys which refer to a secret rather than hold one, e.g. secretName
+var secretReferenceKeyPattern = regexp.MustCompile(`(?i)(name|ref|path|file|mode|class)$`)
+
+func (s *SecretLeak) GetTitle() string {
+	return "Secret Leak Detection"
+}
+
+func (s *SecretLeak) ValidateFiles(ctx context.Context, _ *gitops.Request, files fs.FS, sendMsg func(string)) (*gitops.ValidationResult, error) {
+	result := &gitops.ValidationResult{
+		IsValid:  true,
+		Errors:   make([]error, 0),
+		Findings: make([]gitops.Finding, 0),
+	}
+
+	err := fs.WalkDir(files, ".", func(p string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		allowed, err := matchesAnyGlob(s.AllowPaths, p)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+
+		file, err := files.Open(p)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", p, err)
+		}
+		defer file.Close()
+
+		documents, err := yamlUtil.DecodeDocuments(file)
+		if err != nil {
+			// Files which are not YAML are not of interest to this validator
+			return nil
+		}
+
+		for _, doc := range documents {
+			if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+				continue
+			}
+			obj := doc.Content[0]
+
+			// Documents encrypted with SOPS carry a top-level sops key and are safe to commit
+			if yamlUtil.GetNode(obj, "sops") != nil {
+				continue
+			}
+
+			resource := yamlUtil.ResourceID(obj)
+			allowed, err := matchesAnyGlob(s.AllowResources, resource)
+			if err != nil {
+				return err
+			}
+			if allowed {
+				continue
+			}
+
+			for _, message := range s.inspect(obj) {
+				result.Findings = append(result.Findings, gitops.Finding{
+					Severity: s.Severity,
+					Path:     p,
+					Message:  fmt.Sprintf("%s in %s", message, resource),
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests: %w", err)
+	}
+
+	if len(result.Findings) > 0 {
+		sendMsg(fmt.Sprintf("found %d potential secret leak(s)", len(result.Findings)))
+	}
+
+	return result, nil
+}
+
+// Returns a message for each potential secret found in the object. Messages must not contain the secret itself.
+func (s *SecretLeak) inspect(obj *yaml.Node) []string {
+	messages := make([]string, 0)
+	kind := yamlUtil.GetString(obj, "kind")
+
+	if kind == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			data := yamlUtil.GetNode(obj, field)
+			if data == nil || data.Kind != yaml.MappingNode {
+				continue
+			}
+			for i := 0; i+1 < len(data.Content); i += 2 {
+				messages = append(messages, fmt.Sprintf("unencrypted Secret value at %s.%s", field, data.Content[i].Value))
+			}
+		}
+
+		return messages
+	}
+
+	walkScalars(obj, "", "", func(fieldPath, key string, value *yaml.Node) {
+		for _, p := range secretPatterns {
+			if p.pattern.MatchString(value.Value) {
+				messages = append(messages, fmt.Sprintf("possible %s at %s", p.name, fieldPath))
+				return
+			}
+		}
+
+		suggestiveKey := secretKeyPattern.MatchString(key) && !secretReferenceKeyPattern.MatchString(key)
+		inConfigMapData := kind == "ConfigMap" && strings.HasPrefix(fieldPath, "data.")
+		isEnvValue := strings.HasSuffix(fieldPath, ".value")
+
+		if suggestiveKey && len(value.Value) >= 8 && value.Tag == "!!str" {
+			messages = append(messages, fmt.Sprintf("possible credential in %q at %s", key, fieldPath))
+			return
+		}
+
+		if (suggestiveKey || inConfigMapData || isEnvValue) && s.isHighEntropy(value.Value) {
+			messages = append(messages, fmt.Sprintf("high-entropy string at %s", fieldPath))
+		}
+	})
+
+	return messages
+}
+
+func (s *SecretLeak) isHighEntropy(value string) bool {
+	threshold := s.EntropyThreshold
+	if threshold == 0 {
+		threshold = 4.5
+	}
+	minLength := s.MinLength
+	if minLength == 0 {
+		minLength = 20
+	}
+
+	if len(value) < minLength {
+		return false
+	}
+	for _, r := range value {
+		if r == ' ' || r == '\n' || r == '\t' {
+			return false
+		}
+	}
+
+	return shannonEntropy(value) >= threshold
+}
+
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
+
+// Calls fn for every scalar value in the node with its dotted field path and the key it is held under.
+// Entries of environment variable lists use the variable name as their key.
+func walkScalars(node *yaml.Node, fieldPath, key string, fn func(fieldPath, key string, value *yaml.Node)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		envName := yamlUtil.GetString(node, "name")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			childPath := childKey
+			if fieldPath != "" {
+				childPath = fieldPath + "." + childKey
+			}
+			if childKey == "value" && envName != "" {
+				walkScalars(node.Content[i+1], childPath, envName, fn)
+				continue
+			}
+			walkScalars(node.Content[i+1], childPath, childKey, fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walkScalars(child, fieldPath+"["+strconv.Itoa(i)+"]", key, fn)
+		}
+	case yaml.ScalarNode:
+		fn(fieldPath, key, node)
+	}
+}
+
+// Matches a name against glob patterns using util.MatchGlob, so "**" matches any number of path segments
+func matchesAnyGlob(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := yamlUtil.MatchGlob(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
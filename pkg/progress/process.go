@@ -15,6 +15,7 @@ type ProcessReporter struct {
 	cancel            context.CancelFunc
 	progressionPeriod time.Duration
 	wg                sync.WaitGroup
+	mu                sync.Mutex
 }
 
 type ProcessReporterOptions struct {
@@ -70,10 +71,18 @@ func (p *ProcessReporter) Done() {
 	p.wg.Wait()
 }
 
+// Safe for concurrent use
 func (p *ProcessReporter) Increment(delta int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.ProgressCount += delta
 }
 
 func (p *ProcessReporter) sendProgress() {
-	p.Reporter.Progress("%s %d/%d %s", p.Template.PastAction, p.ProgressCount, p.TotalCount, p.Template.Subject)
+	p.mu.Lock()
+	progressCount := p.ProgressCount
+	p.mu.Unlock()
+
+	p.Reporter.Progress("%s %d/%d %s", p.Template.PastAction, progressCount, p.TotalCount, p.Template.Subject)
 }
@@ -81,6 +81,13 @@ func (p *Reporter) Failure(s string, args ...any) {
 	}
 }
 
+func (p *Reporter) Warning(s string, args ...any) {
+	p.progressChan <- &pb.Progress{
+		Kind:   pb.ProgressKind_WARNING,
+		Status: fmt.Sprintf(s, args...),
+	}
+}
+
 type Result struct {
 	Success string
 	Failure string
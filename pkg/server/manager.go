@@ -11,6 +11,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -31,6 +32,7 @@ type Manager struct {
 
 type ManagerOpts struct {
 	GitOptions GitOptions
+	Pruning    PruneOptions
 }
 
 type GitOptions struct {
@@ -104,6 +106,7 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 		return respondWithError(fmt.Errorf("failed to create gitops target: %w", err))
 	}
 	response.Environment.Repository = &target.Repository
+	req.Target = target
 
 	gitopsService := gitops.NewService(m.report, gitops.ServiceOptions{
 		EnvironmentName:  req.Environment,
@@ -150,111 +153,62 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 	})
 
 	if len(m.flow.Processors.Mutators) > 0 {
-		errs := make([]error, 0)
-
-		nextFns := make([]func(io.Reader), len(m.flow.Processors.Mutators)+1)
-
-		for i, mutator := range m.flow.Processors.Mutators {
-			nextFns[i] = func(input io.Reader) {
-				slog.Debug("running mutator", "mutator", mutator.GetTitle())
+		files, err := listFiles(req.Paths.UpdatedManifestsDir)
+		if err != nil {
+			return respondWithError(fmt.Errorf("failed to list manifests: %w", err))
+		}
 
-				output := &bytes.Buffer{}
+		mutationProcessReport.Start(ctx)
 
-				readBytes := &bytes.Buffer{}
-				teedInput := io.TeeReader(input, readBytes)
+		fileErrs, err := util.ParallelMap(ctx, m.flow.Processors.Concurrency, files, func(ctx context.Context, path string) []error {
+			defer mutationProcessReport.Increment(1)
+			return m.mutateFile(ctx, req, path)
+		})
 
-				mutateErr := mutator.MutateFile(
-					ctx,
-					req,
-					teedInput,
-					output,
-					m.report.BasicProgress,
-				)
-				if mutateErr != nil {
-					errs = append(errs, mutateErr)
-				}
+		mutationProcessReport.Done()
 
-				var outputReader io.Reader
+		if err != nil {
+			m.report.Failure("Mutation cancelled")
+			return respondWithError(fmt.Errorf("mutation cancelled: %w", err))
+		}
 
-				if output.Len() == 0 || mutateErr != nil {
-					outputReader = io.MultiReader(readBytes, input)
-				} else {
-					outputReader = bytes.NewReader(output.Bytes())
-				}
+		errs := slices.Concat(fileErrs...)
+		if len(errs) > 0 {
+			slog.Error("errors occured during mutation", "count", len(errs))
+			m.report.Failure("%d error(s) occured during mutation", len(errs))
 
-				nextFns[i+1](outputReader)
-			}
+			return respondWithError(errors.New(strings.Join(util.Map(errs, func(e error) string { return e.Error() }), "\n")))
 		}
+	} else {
+		m.report.Progress("no mutations to be run")
+	}
 
-		mutationProcessReport.Start(ctx)
+	m.report.Success("Successfully mutated %d manifests", mutationProcessReport.ProgressCount)
 
-		err = filepath.WalkDir(req.Paths.UpdatedManifestsDir, func(path string, d os.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return walkErr
-			}
+	if len(m.flow.Processors.FileSetMutators) > 0 {
+		m.report.Heading("Transforming manifest set")
 
+		manifests := gitops.NewDirFileSet(req.Paths.UpdatedManifestsDir)
+
+		for _, mutator := range m.flow.Processors.FileSetMutators {
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
+				return respondWithError(ctx.Err())
 			default:
 			}
 
-			if !d.IsDir() {
-				file, err := os.OpenFile(path, os.O_RDWR, 0644)
-				if err != nil {
-					return fmt.Errorf("failed to open file: %w", err)
-				}
-				defer file.Close()
-
-				var writeErr error
-
-				nextFns[len(nextFns)-1] = func(buf io.Reader) {
-					var written int64
-					writeErr = file.Truncate(0)
-					if writeErr != nil {
-						return
-					}
-					_, writeErr = file.Seek(0, io.SeekStart)
-					if writeErr != nil {
-						return
-					}
-
-					written, writeErr = io.Copy(file, buf)
-					slog.Debug("written mutated file", "bytes", written, "file", file.Name())
-				}
+			slog.Debug("running file set mutator", "title", mutator.GetTitle())
+			m.report.Progress("running %s", mutator.GetTitle())
 
-				nextFns[0](file)
-				if writeErr != nil {
-					return fmt.Errorf("failed to write to file: %w", writeErr)
-				}
-				mutationProcessReport.Increment(1)
+			err = mutator.MutateFiles(ctx, req, manifests, m.report.BasicProgress)
+			if err != nil {
+				m.report.Failure("Failed to run %s", mutator.GetTitle())
+				return respondWithError(fmt.Errorf("failed to run %s: %w", mutator.GetTitle(), err))
 			}
-
-			return nil
-		})
-
-		mutationProcessReport.Done()
-
-		if len(errs) > 0 {
-			slog.Error("errors occured during mutation", "count", len(errs))
-			m.report.Failure("%d error(s) occured during mutation", len(errs))
-
-			messages := util.Map(errs, func(e error) string {
-				if e != nil {
-					return e.Error()
-				} else {
-					return ""
-				}
-			})
-			joinedMessage := strings.Join(messages, "\n")
-			err = errors.New(joinedMessage)
-			return respondWithError(err)
 		}
-	} else {
-		m.report.Progress("no mutations to be run")
-	}
 
-	m.report.Success("Successfully mutated %d manifests", mutationProcessReport.ProgressCount)
+		m.report.Success("Successfully transformed manifest set")
+	}
 
 	m.report.Heading("Validating manifests")
 
@@ -268,59 +222,38 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 		},
 	})
 
+	findings := make([]gitops.Finding, 0)
+
 	if len(m.flow.Processors.Validators) > 0 {
-		errs := make([]error, 0)
-		successfulValidationResults := make([]*gitops.ValidationResult, 0)
-		failedValidationResults := make(map[string][]*gitops.ValidationResult, 0)
+		files, err := listFiles(req.Paths.UpdatedManifestsDir)
+		if err != nil {
+			return respondWithError(fmt.Errorf("failed to list manifests: %w", err))
+		}
 
 		validationProcessReport.Start(ctx)
 
-		err = filepath.WalkDir(req.Paths.UpdatedManifestsDir, func(path string, d os.DirEntry, walkErr error) error {
-			if walkErr != nil {
-				return walkErr
-			}
+		validations, err := util.ParallelMap(ctx, m.flow.Processors.Concurrency, files, func(ctx context.Context, path string) *fileValidation {
+			defer validationProcessReport.Increment(1)
+			return m.validateFile(ctx, req, path)
+		})
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
+		validationProcessReport.Done()
 
-			if !d.IsDir() {
-				file, err := os.OpenFile(path, os.O_RDONLY, 0644)
-				if err != nil {
-					return fmt.Errorf("failed to open file: %w", err)
-				}
-				defer file.Close()
-
-				for _, validator := range m.flow.Processors.Validators {
-					slog.Debug("running validator", "title", validator.GetTitle())
-
-					result, err := validator.ValidateFile(
-						ctx,
-						file,
-						m.report.BasicProgress,
-					)
-					if result.IsValid {
-						successfulValidationResults = append(successfulValidationResults, result)
-					} else {
-						relativePath, err := filepath.Rel(req.Paths.UpdatedManifestsDir, path)
-						if err != nil {
-							return fmt.Errorf("failed to form relative path: %w", err)
-						}
-						failedValidationResults[relativePath] = append(failedValidationResults[relativePath], result)
-					}
-					if err != nil {
-						errs = append(errs, fmt.Errorf("failed to validate %s: %w", path, err))
-					}
-				}
+		if err != nil {
+			m.report.Failure("Validation cancelled")
+			return respondWithError(fmt.Errorf("validation cancelled: %w", err))
+		}
 
-				validationProcessReport.Increment(1)
+		errs := make([]error, 0)
+		failedValidations := make([]*fileValidation, 0)
+		fileFindings := make([]gitops.Finding, 0)
+		for _, validation := range validations {
+			errs = append(errs, validation.errs...)
+			if len(validation.failedResults) > 0 {
+				failedValidations = append(failedValidations, validation)
 			}
-			return nil
-		})
-
-		validationProcessReport.Done()
+			fileFindings = append(fileFindings, validation.findings...)
+		}
 
 		if len(errs) > 0 {
 			slog.Error("errors occured during validation", "count", len(errs))
@@ -329,23 +262,96 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 			return respondWithError(errors.New(strings.Join(util.Map(errs, func(e error) string { return e.Error() }), "\n")))
 		}
 
-		if len(failedValidationResults) > 0 {
-			slog.Info("validations failed", "count", len(failedValidationResults))
-			m.report.Failure("%d validation(s) failed", len(failedValidationResults))
+		blockingFindings, nonBlockingFindings := m.partitionFindings(req, fileFindings)
+
+		if len(failedValidations) > 0 || len(blockingFindings) > 0 {
+			slog.Info("validations failed", "count", len(failedValidations)+len(blockingFindings))
+			m.report.Failure("%d validation(s) failed", len(failedValidations)+len(blockingFindings))
 			errorStrings := make([]string, 0)
-			for path, results := range failedValidationResults {
-				for _, result := range results {
-					errorStrings = append(errorStrings, fmt.Sprintf("%s: %s", path, strings.Join(util.Map(result.Errors, func(e error) string { return e.Error() }), "\n")))
+			for _, validation := range failedValidations {
+				for _, result := range validation.failedResults {
+					errorStrings = append(errorStrings, fmt.Sprintf("%s: %s", validation.path, strings.Join(util.Map(result.Errors, func(e error) string { return e.Error() }), "\n")))
 				}
 			}
+			for _, finding := range blockingFindings {
+				errorStrings = append(errorStrings, fmt.Sprintf("%s: %s", finding.Path, finding.Message))
+			}
 			return respondWithError(fmt.Errorf("invalid manifests: \n%s", strings.Join(errorStrings, "\n\n")))
 		}
+
+		findings = append(findings, nonBlockingFindings...)
 	} else {
 		m.report.Progress("no validations to be run")
 	}
 
 	m.report.Success("Successfully validated %d manifests", validationProcessReport.ProgressCount)
 
+	if len(m.flow.Processors.SetValidators) > 0 {
+		m.report.Heading("Validating manifest set")
+
+		errs := make([]error, 0)
+		failedValidations := make([]*failedSetValidation, 0)
+		setFindings := make([]gitops.Finding, 0)
+		manifests := os.DirFS(req.Paths.UpdatedManifestsDir)
+
+		for _, validator := range m.flow.Processors.SetValidators {
+			select {
+			case <-ctx.Done():
+				return respondWithError(ctx.Err())
+			default:
+			}
+
+			slog.Debug("running set validator", "title", validator.GetTitle())
+			m.report.Progress("running %s", validator.GetTitle())
+
+			result, err := validator.ValidateFiles(ctx, req, manifests, m.report.BasicProgress)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to run %s: %w", validator.GetTitle(), err))
+				continue
+			}
+			if !result.IsValid {
+				failedValidations = append(failedValidations, &failedSetValidation{title: validator.GetTitle(), result: result})
+			}
+			setFindings = append(setFindings, annotateFindings(result.Findings, "", validator.GetTitle())...)
+		}
+
+		if len(errs) > 0 {
+			slog.Error("errors occured during set validation", "count", len(errs))
+			m.report.Failure("%d error(s) occured during set validation", len(errs))
+
+			return respondWithError(errors.New(strings.Join(util.Map(errs, func(e error) string { return e.Error() }), "\n")))
+		}
+
+		blockingFindings, nonBlockingFindings := m.partitionFindings(req, setFindings)
+
+		if len(failedValidations) > 0 || len(blockingFindings) > 0 {
+			slog.Info("set validations failed", "count", len(failedValidations)+len(blockingFindings))
+			m.report.Failure("%d set validation(s) failed", len(failedValidations)+len(blockingFindings))
+			errorStrings := make([]string, 0)
+			for _, validation := range failedValidations {
+				errorStrings = append(errorStrings, fmt.Sprintf("%s:\n%s", validation.title, strings.Join(util.Map(validation.result.Errors, func(e error) string { return e.Error() }), "\n")))
+			}
+			for _, finding := range blockingFindings {
+				errorStrings = append(errorStrings, fmt.Sprintf("%s: %s", finding.Validator, finding))
+			}
+			return respondWithError(fmt.Errorf("invalid manifest set: \n%s", strings.Join(errorStrings, "\n\n")))
+		}
+
+		findings = append(findings, nonBlockingFindings...)
+
+		m.report.Success("Successfully validated manifest set")
+	}
+
+	for _, finding := range findings {
+		if finding.Severity == gitops.SeverityWarning {
+			m.report.Warning("%s (%s)", finding, finding.Validator)
+		} else {
+			m.report.Progress("%s (%s)", finding, finding.Validator)
+		}
+	}
+	req.Findings = findings
+	response.Findings = findings
+
 	m.report.Heading("Copying files to the configuration repository")
 
 	err = strategies.FileCopy.CopyFiles(os.DirFS(req.Paths.UpdatedManifestsDir), path.Join(req.Paths.RepositoryDir, target.Directory), m.report.BasicProgress)
@@ -394,6 +400,15 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 
 	m.report.Heading("Creating review")
 
+	req.Diff = m.diffStats(gitopsService.DiffStats)
+	req.CommitDiff = m.diffStats(gitopsService.CommitDiffStats)
+	req.Review, err = m.renderReview(req, target)
+	if err != nil {
+		m.report.Failure("Failed to render review")
+		return respondWithError(err)
+	}
+	m.report.Progress("%s", req.Review.Title)
+
 	if !req.DryRun {
 		response.ReviewResult, err = strategies.CreateReview.CreateReview(ctx, req, target, m.report.BasicProgress)
 		if err != nil {
@@ -408,6 +423,9 @@ func (m *Manager) ProcessRequest(ctx context.Context, req *gitops.Request) (*git
 		}
 
 		m.report.Progress("Review URL: %s", response.ReviewResult.URL)
+		if response.ReviewResult.Assignment != nil {
+			m.report.Progress("%s", response.ReviewResult.Assignment)
+		}
 		m.report.Success("Created review")
 	} else {
 		response.ReviewResult.Created = true
@@ -444,3 +462,199 @@ End:
 
 	return response, nil
 }
+
+// Summarises changes to the next branch, returning nil if they cannot be compared
+func (m *Manager) diffStats(compare func() (*gitops.DiffStats, error)) *gitops.DiffStats {
+	diffStats, err := compare()
+	if err != nil {
+		slog.Warn("failed to compare changes", "error", err)
+		m.report.Progress("unable to summarise changes: %s", err)
+		return nil
+	}
+
+	return diffStats
+}
+
+// Renders the review title, description and update comment
+func (m *Manager) renderReview(req *gitops.Request, target *gitops.Target) (*gitops.ReviewContent, error) {
+	reviewTemplate := m.flow.ReviewTemplate
+	if reviewTemplate == nil {
+		reviewTemplate = &gitops.ReviewTemplate{}
+	}
+
+	return reviewTemplate.Render(gitops.NewReviewTemplateData(req, target))
+}
+
+// Splits findings into those which fail the request and those which do not, promoting warnings where configured
+func (m *Manager) partitionFindings(req *gitops.Request, findings []gitops.Finding) ([]gitops.Finding, []gitops.Finding) {
+	promoteWarnings := slices.Contains(m.flow.Processors.WarningsAsErrors, req.Environment)
+
+	blocking := make([]gitops.Finding, 0)
+	nonBlocking := make([]gitops.Finding, 0)
+
+	for _, finding := range findings {
+		if finding.Severity == gitops.SeverityWarning && promoteWarnings {
+			finding.Severity = gitops.SeverityError
+		}
+
+		if finding.Severity == gitops.SeverityError {
+			blocking = append(blocking, finding)
+		} else {
+			nonBlocking = append(nonBlocking, finding)
+		}
+	}
+
+	return blocking, nonBlocking
+}
+
+func annotateFindings(findings []gitops.Finding, path, validator string) []gitops.Finding {
+	annotated := make([]gitops.Finding, len(findings))
+	for i, finding := range findings {
+		if finding.Path == "" {
+			finding.Path = path
+		}
+		if finding.Validator == "" {
+			finding.Validator = validator
+		}
+		annotated[i] = finding
+	}
+
+	return annotated
+}
+
+// Describes a file in the manifests directory to the processors
+func newFileContext(manifestsDir, fullPath string, content []byte) (*gitops.FileContext, error) {
+	relativePath, err := filepath.Rel(manifestsDir, fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to form relative path: %w", err)
+	}
+	relativePath = filepath.ToSlash(relativePath)
+
+	return &gitops.FileContext{
+		Path:        relativePath,
+		Size:        int64(len(content)),
+		ContentType: gitops.DetectContentType(relativePath, content[:min(len(content), 512)]),
+	}, nil
+}
+
+// Returns the paths of all files below a directory in lexical order
+func listFiles(dir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !d.IsDir() {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+
+	return files, err
+}
+
+// Runs the mutator chain over a single file. A mutator which errors or writes nothing passes its input on to the next.
+func (m *Manager) mutateFile(ctx context.Context, req *gitops.Request, path string) []error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return []error{fmt.Errorf("failed to open file: %w", err)}
+	}
+	defer file.Close()
+
+	original, err := io.ReadAll(file)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read file: %w", err)}
+	}
+	fileContext, err := newFileContext(req.Paths.UpdatedManifestsDir, path, original)
+	if err != nil {
+		return []error{err}
+	}
+
+	errs := make([]error, 0)
+	content := original
+	for _, mutator := range m.flow.Processors.Mutators {
+		if ctx.Err() != nil {
+			return append(errs, ctx.Err())
+		}
+
+		slog.Debug("running mutator", "mutator", mutator.GetTitle(), "file", fileContext.Path)
+
+		output := &bytes.Buffer{}
+		err = mutator.MutateFile(ctx, req, fileContext, bytes.NewReader(content), output, m.report.BasicProgress)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", fileContext.Path, mutator.GetTitle(), err))
+			continue
+		}
+		if output.Len() > 0 {
+			content = output.Bytes()
+		}
+	}
+
+	if bytes.Equal(content, original) {
+		return errs
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = file.WriteAt(content, 0)
+	}
+	if err != nil {
+		return append(errs, fmt.Errorf("failed to write to file: %w", err))
+	}
+	slog.Debug("written mutated file", "bytes", len(content), "file", file.Name())
+
+	return errs
+}
+
+// A failed result of a set validator, kept in the order the set validators ran
+type failedSetValidation struct {
+	title  string
+	result *gitops.ValidationResult
+}
+
+type fileValidation struct {
+	path          string
+	failedResults []*gitops.ValidationResult
+	findings      []gitops.Finding
+	errs          []error
+}
+
+// Runs every validator over a single file, each reading the file from the start
+func (m *Manager) validateFile(ctx context.Context, req *gitops.Request, path string) *fileValidation {
+	validation := &fileValidation{path: path}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		validation.errs = append(validation.errs, fmt.Errorf("failed to read %s: %w", path, err))
+		return validation
+	}
+	fileContext, err := newFileContext(req.Paths.UpdatedManifestsDir, path, content)
+	if err != nil {
+		validation.errs = append(validation.errs, err)
+		return validation
+	}
+	relativePath := fileContext.Path
+	validation.path = relativePath
+
+	for _, validator := range m.flow.Processors.Validators {
+		if ctx.Err() != nil {
+			validation.errs = append(validation.errs, ctx.Err())
+			return validation
+		}
+
+		slog.Debug("running validator", "title", validator.GetTitle(), "file", relativePath)
+
+		result, err := validator.ValidateFile(ctx, req, fileContext, bytes.NewReader(content), m.report.BasicProgress)
+		if err != nil {
+			validation.errs = append(validation.errs, fmt.Errorf("failed to validate %s: %w", relativePath, err))
+			continue
+		}
+		if !result.IsValid {
+			validation.failedResults = append(validation.failedResults, result)
+		}
+		validation.findings = append(validation.findings, annotateFindings(result.Findings, relativePath, validator.GetTitle())...)
+	}
+
+	return validation
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/flow"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+type PruneOptions struct {
+	Repositories []gitops.Repository // Configuration repositories which are pruned periodically
+	Interval     time.Duration       // How often the repositories are pruned. Periodic pruning is disabled if zero.
+	TTL          time.Duration       // How long a source branch may go without updates before it is pruned. Pruning by age is disabled if zero.
+}
+
+type PruneRequest struct {
+	Repository               gitops.Repository
+	Environment              string   // Only prunes the source branches of this environment if set
+	AppName                  string   // Only prunes the source branches of this app if set
+	DeletedUpdateIdentifiers []string // Prunes the source branches of these update identifiers regardless of age
+	DryRun                   bool
+}
+
+// A source branch which was pruned, along with its open review if it had one
+type PrunedBranch struct {
+	Branch        string
+	Source        *gitops.SourceBranch
+	Reason        string
+	ReviewURL     string // The URL of the closed review, if there was one
+	BranchDeleted bool
+	Error         string // Set if the review could not be closed or the branch could not be deleted
+}
+
+// How long a review must go without updates before it is pruned for missing its source branch
+const orphanedReviewGracePeriod = 10 * time.Minute
+
+type pruner struct {
+	flow    *flow.Flow
+	options *PruneOptions
+}
+
+// A source branch and its open review, either of which may be missing
+type pruneCandidate struct {
+	source *gitops.SourceBranch
+	branch *gitops.RepositoryBranch
+	review *gitops.OpenReview
+}
+
+// Closes the open reviews of stale source branches and deletes the branches. Source branches are stale if their update
+// identifier was deleted, if neither they nor their review were updated within the TTL, or if their review remains open
+// after the branch was deleted and was not updated within the grace period.
+func (p *pruner) prune(ctx context.Context, req *PruneRequest) ([]*PrunedBranch, error) {
+	reviewPruner, ok := p.flow.Strategies.CreateReview.(gitops.ReviewPruner)
+	if !ok {
+		return nil, errors.New("the reviewer does not support pruning")
+	}
+	branchParser, ok := p.flow.Strategies.Target.(gitops.SourceBranchParser)
+	if !ok {
+		return nil, errors.New("the targeter does not support pruning")
+	}
+
+	// Reviews are listed first so that the branch of a review created in the meantime is listed too
+	reviews, err := reviewPruner.ListOpenReviews(ctx, req.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open reviews: %w", err)
+	}
+	branches, err := reviewPruner.ListBranches(ctx, req.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	candidates := make(map[string]*pruneCandidate)
+	candidate := func(name string) *pruneCandidate {
+		if c, ok := candidates[name]; ok {
+			return c
+		}
+		source, ok := branchParser.ParseSourceBranch(name)
+		if !ok || (req.Environment != "" && source.Environment != req.Environment) || (req.AppName != "" && source.AppName != req.AppName) {
+			return nil
+		}
+		candidates[name] = &pruneCandidate{source: source}
+		return candidates[name]
+	}
+	for _, branch := range branches {
+		if c := candidate(branch.Name); c != nil {
+			c.branch = branch
+		}
+	}
+	for _, review := range reviews {
+		if c := candidate(review.SourceBranch); c != nil {
+			c.review = review
+		}
+	}
+
+	// A branch can be missed while it is pushed to during the listing, so its review is only pruned if it is missing twice
+	if slices.ContainsFunc(slices.Collect(maps.Values(candidates)), func(c *pruneCandidate) bool { return c.branch == nil }) {
+		branches, err := reviewPruner.ListBranches(ctx, req.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches: %w", err)
+		}
+		for _, branch := range branches {
+			if c, ok := candidates[branch.Name]; ok && c.branch == nil {
+				c.branch = branch
+			}
+		}
+	}
+
+	pruned := make([]*PrunedBranch, 0)
+	for _, name := range slices.Sorted(maps.Keys(candidates)) {
+		c := candidates[name]
+		reason := p.staleReason(c, req.DeletedUpdateIdentifiers)
+		if reason == "" {
+			continue
+		}
+
+		prunedBranch := &PrunedBranch{
+			Branch: name,
+			Source: c.source,
+			Reason: reason,
+		}
+		pruned = append(pruned, prunedBranch)
+		if c.review != nil {
+			prunedBranch.ReviewURL = c.review.URL
+		}
+		if req.DryRun {
+			continue
+		}
+
+		err := p.pruneBranch(ctx, reviewPruner, req.Repository, c, reason, prunedBranch)
+		if err != nil {
+			slog.Warn("failed to prune branch", "repository", req.Repository.URL, "branch", name, "error", err)
+			prunedBranch.Error = err.Error()
+		}
+	}
+
+	return pruned, nil
+}
+
+// Returns why a source branch is stale, or an empty string if it is not
+func (p *pruner) staleReason(c *pruneCandidate, deletedUpdateIdentifiers []string) string {
+	if slices.Contains(deletedUpdateIdentifiers, c.source.UpdateIdentifier) {
+		return fmt.Sprintf("the update identifier %s was deleted", c.source.UpdateIdentifier)
+	}
+	if c.branch == nil {
+		if time.Since(c.review.UpdatedAt) < orphanedReviewGracePeriod {
+			return ""
+		}
+		return "the source branch no longer exists"
+	}
+
+	updatedAt := c.branch.UpdatedAt
+	if c.review != nil && c.review.UpdatedAt.After(updatedAt) {
+		updatedAt = c.review.UpdatedAt
+	}
+	if p.options.TTL > 0 && time.Since(updatedAt) > p.options.TTL {
+		return fmt.Sprintf("it has not been updated for over %s", p.options.TTL)
+	}
+
+	return ""
+}
+
+func (p *pruner) pruneBranch(ctx context.Context, reviewPruner gitops.ReviewPruner, repository gitops.Repository, c *pruneCandidate, reason string, prunedBranch *PrunedBranch) error {
+	if c.review != nil {
+		err := reviewPruner.CloseReview(ctx, c.review, fmt.Sprintf("Closed by gitops-manager as %s.", reason))
+		if err != nil {
+			return fmt.Errorf("failed to close review: %w", err)
+		}
+	}
+	if c.branch != nil {
+		err := reviewPruner.RemoveBranch(ctx, repository, c.branch.Name)
+		if err != nil {
+			return fmt.Errorf("failed to delete branch: %w", err)
+		}
+		prunedBranch.BranchDeleted = true
+	}
+
+	return nil
+}
+
+// Prunes the configured repositories every interval until the context is cancelled
+func (p *pruner) run(ctx context.Context) {
+	ticker := time.NewTicker(p.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, repository := range p.options.Repositories {
+			pruned, err := p.prune(ctx, &PruneRequest{Repository: repository})
+			if err != nil {
+				slog.Error("failed to prune repository", "repository", repository.URL, "error", err)
+				continue
+			}
+			for _, prunedBranch := range pruned {
+				slog.Info("pruned branch", "repository", repository.URL, "branch", prunedBranch.Branch, "reason", prunedBranch.Reason,
+					"reviewUrl", prunedBranch.ReviewURL, "branchDeleted", prunedBranch.BranchDeleted, "error", prunedBranch.Error)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
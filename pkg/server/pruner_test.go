@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tvandinther/gitops-manager/pkg/flow"
+	"github.com/tvandinther/gitops-manager/pkg/gitops"
+)
+
+// A reviewer which serves reviews and branches from memory and records what was pruned
+type fakeReviewPruner struct {
+	gitops.Reviewer
+
+	reviews []*gitops.OpenReview
+	// The branches returned by each call to ListBranches. The last listing is repeated for any further calls.
+	branchListings [][]*gitops.RepositoryBranch
+	listCalls      int
+	closed         []string
+	removed        []string
+}
+
+func (f *fakeReviewPruner) ListOpenReviews(ctx context.Context, repository gitops.Repository) ([]*gitops.OpenReview, error) {
+	return f.reviews, nil
+}
+
+func (f *fakeReviewPruner) CloseReview(ctx context.Context, review *gitops.OpenReview, comment string) error {
+	f.closed = append(f.closed, review.URL)
+	return nil
+}
+
+func (f *fakeReviewPruner) ListBranches(ctx context.Context, repository gitops.Repository) ([]*gitops.RepositoryBranch, error) {
+	listing := f.branchListings[min(f.listCalls, len(f.branchListings)-1)]
+	f.listCalls++
+	return listing, nil
+}
+
+func (f *fakeReviewPruner) RemoveBranch(ctx context.Context, repository gitops.Repository, branch string) error {
+	f.removed = append(f.removed, branch)
+	return nil
+}
+
+// A targeter whose source branches are named src/{environment}/{app}/{update identifier}
+type fakeSourceBranchParser struct {
+	gitops.Targeter
+}
+
+func (f *fakeSourceBranchParser) ParseSourceBranch(branch string) (*gitops.SourceBranch, bool) {
+	parts := strings.Split(branch, "/")
+	if len(parts) != 4 || parts[0] != "src" {
+		return nil, false
+	}
+
+	return &gitops.SourceBranch{Environment: parts[1], AppName: parts[2], UpdateIdentifier: parts[3]}, true
+}
+
+func newTestPruner(reviewPruner *fakeReviewPruner, ttl time.Duration) *pruner {
+	return &pruner{
+		flow: flow.New(&flow.Strategies{
+			Target:       &fakeSourceBranchParser{},
+			CreateReview: reviewPruner,
+		}),
+		options: &PruneOptions{TTL: ttl},
+	}
+}
+
+func prunedBranchNames(pruned []*PrunedBranch) []string {
+	names := make([]string, 0, len(pruned))
+	for _, prunedBranch := range pruned {
+		names = append(names, prunedBranch.Branch)
+	}
+	return names
+}
+
+func TestPrunerStaleReason(t *testing.T) {
+	now := time.Now()
+	source := &gitops.SourceBranch{Environment: "dev", AppName: "app", UpdateIdentifier: "1"}
+
+	tests := []struct {
+		name       string
+		ttl        time.Duration
+		deletedIds []string
+		candidate  *pruneCandidate
+		want       string
+	}{
+		{
+			name:       "deleted update identifier",
+			ttl:        time.Hour,
+			deletedIds: []string{"1"},
+			candidate:  &pruneCandidate{source: source, branch: &gitops.RepositoryBranch{UpdatedAt: now}},
+			want:       "the update identifier 1 was deleted",
+		},
+		{
+			name:      "orphaned review",
+			candidate: &pruneCandidate{source: source, review: &gitops.OpenReview{UpdatedAt: now.Add(-time.Hour)}},
+			want:      "the source branch no longer exists",
+		},
+		{
+			name:      "orphaned review within the grace period",
+			candidate: &pruneCandidate{source: source, review: &gitops.OpenReview{UpdatedAt: now}},
+			want:      "",
+		},
+		{
+			name:      "branch older than the TTL",
+			ttl:       time.Hour,
+			candidate: &pruneCandidate{source: source, branch: &gitops.RepositoryBranch{UpdatedAt: now.Add(-2 * time.Hour)}},
+			want:      "it has not been updated for over 1h0m0s",
+		},
+		{
+			name: "branch older than the TTL with a recently updated review",
+			ttl:  time.Hour,
+			candidate: &pruneCandidate{
+				source: source,
+				branch: &gitops.RepositoryBranch{UpdatedAt: now.Add(-2 * time.Hour)},
+				review: &gitops.OpenReview{UpdatedAt: now},
+			},
+			want: "",
+		},
+		{
+			name:      "pruning by age disabled",
+			candidate: &pruneCandidate{source: source, branch: &gitops.RepositoryBranch{UpdatedAt: now.Add(-24 * time.Hour)}},
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPruner(&fakeReviewPruner{}, tt.ttl)
+			if got := p.staleReason(tt.candidate, tt.deletedIds); got != tt.want {
+				t.Errorf("staleReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrunerPrunesStaleBranches(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	reviewPruner := &fakeReviewPruner{
+		reviews: []*gitops.OpenReview{
+			{URL: "review/stale", SourceBranch: "src/dev/app/stale", UpdatedAt: old},
+			{URL: "review/orphaned", SourceBranch: "src/dev/app/orphaned", UpdatedAt: old},
+			{URL: "review/fresh", SourceBranch: "src/dev/app/fresh", UpdatedAt: time.Now()},
+		},
+		branchListings: [][]*gitops.RepositoryBranch{{
+			{Name: "main", UpdatedAt: old},
+			{Name: "src/dev/app/stale", UpdatedAt: old},
+			{Name: "src/dev/app/fresh", UpdatedAt: time.Now()},
+			{Name: "src/dev/app/deleted", UpdatedAt: time.Now()},
+		}},
+	}
+	p := newTestPruner(reviewPruner, 24*time.Hour)
+
+	pruned, err := p.prune(context.Background(), &PruneRequest{DeletedUpdateIdentifiers: []string{"deleted"}})
+	if err != nil {
+		t.Fatalf("prune() error = %s", err)
+	}
+
+	if names := prunedBranchNames(pruned); !slices.Equal(names, []string{"src/dev/app/deleted", "src/dev/app/orphaned", "src/dev/app/stale"}) {
+		t.Errorf("pruned %v", names)
+	}
+	if !slices.Equal(reviewPruner.closed, []string{"review/orphaned", "review/stale"}) {
+		t.Errorf("closed reviews %v", reviewPruner.closed)
+	}
+	if !slices.Equal(reviewPruner.removed, []string{"src/dev/app/deleted", "src/dev/app/stale"}) {
+		t.Errorf("removed branches %v", reviewPruner.removed)
+	}
+	if pruned[1].BranchDeleted || pruned[1].ReviewURL != "review/orphaned" {
+		t.Errorf("orphaned review pruned as %+v", pruned[1])
+	}
+}
+
+func TestPrunerKeepsReviewWhoseBranchIsListedAgain(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	reviewPruner := &fakeReviewPruner{
+		reviews: []*gitops.OpenReview{{URL: "review/1", SourceBranch: "src/dev/app/1", UpdatedAt: old}},
+		branchListings: [][]*gitops.RepositoryBranch{
+			{},
+			{{Name: "src/dev/app/1", UpdatedAt: time.Now()}},
+		},
+	}
+	p := newTestPruner(reviewPruner, 24*time.Hour)
+
+	pruned, err := p.prune(context.Background(), &PruneRequest{})
+	if err != nil {
+		t.Fatalf("prune() error = %s", err)
+	}
+
+	if len(pruned) > 0 {
+		t.Errorf("pruned %v although the branch exists", prunedBranchNames(pruned))
+	}
+	if reviewPruner.listCalls != 2 {
+		t.Errorf("listed branches %d times, want 2", reviewPruner.listCalls)
+	}
+}
+
+func TestPrunerFiltersByEnvironmentAndApp(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	reviewPruner := &fakeReviewPruner{
+		branchListings: [][]*gitops.RepositoryBranch{{
+			{Name: "src/dev/app/1", UpdatedAt: old},
+			{Name: "src/dev/other/1", UpdatedAt: old},
+			{Name: "src/prod/app/1", UpdatedAt: old},
+		}},
+	}
+	p := newTestPruner(reviewPruner, 24*time.Hour)
+
+	pruned, err := p.prune(context.Background(), &PruneRequest{Environment: "dev", AppName: "app"})
+	if err != nil {
+		t.Fatalf("prune() error = %s", err)
+	}
+
+	if names := prunedBranchNames(pruned); !slices.Equal(names, []string{"src/dev/app/1"}) {
+		t.Errorf("pruned %v, want [src/dev/app/1]", names)
+	}
+	if reviewPruner.listCalls != 1 {
+		t.Errorf("listed branches %d times, want 1", reviewPruner.listCalls)
+	}
+}
+
+func TestPrunerDryRun(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	reviewPruner := &fakeReviewPruner{
+		reviews:        []*gitops.OpenReview{{URL: "review/1", SourceBranch: "src/dev/app/1", UpdatedAt: old}},
+		branchListings: [][]*gitops.RepositoryBranch{{{Name: "src/dev/app/1", UpdatedAt: old}}},
+	}
+	p := newTestPruner(reviewPruner, 24*time.Hour)
+
+	pruned, err := p.prune(context.Background(), &PruneRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("prune() error = %s", err)
+	}
+
+	if len(pruned) != 1 || pruned[0].ReviewURL != "review/1" || pruned[0].BranchDeleted {
+		t.Errorf("pruned %+v, want only a report of src/dev/app/1", pruned)
+	}
+	if len(reviewPruner.closed) > 0 || len(reviewPruner.removed) > 0 {
+		t.Errorf("closed %v and removed %v in a dry run", reviewPruner.closed, reviewPruner.removed)
+	}
+}
+
+func TestPrunerRequiresPruningSupport(t *testing.T) {
+	p := &pruner{
+		flow:    flow.New(&flow.Strategies{Target: &fakeSourceBranchParser{}}),
+		options: &PruneOptions{},
+	}
+	_, err := p.prune(context.Background(), &PruneRequest{})
+	if err == nil || err.Error() != "the reviewer does not support pruning" {
+		t.Errorf("prune() error = %v, want the reviewer to be unsupported", err)
+	}
+
+	p.flow.Strategies.CreateReview = &fakeReviewPruner{}
+	p.flow.Strategies.Target = nil
+	_, err = p.prune(context.Background(), &PruneRequest{})
+	if err == nil || err.Error() != "the targeter does not support pruning" {
+		t.Errorf("prune() error = %v, want the targeter to be unsupported", err)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,8 @@ import (
 	"log/slog"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Server struct {
@@ -70,6 +73,11 @@ func (s *Server) Run() error {
 	grpcServer := grpc.NewServer()
 	pb.RegisterGitOpsServer(grpcServer, s)
 
+	if s.managerOpts.Pruning.Interval > 0 {
+		slog.Info("pruning stale reviews periodically", "interval", s.managerOpts.Pruning.Interval, "ttl", s.managerOpts.Pruning.TTL)
+		go s.pruner().run(context.Background())
+	}
+
 	http.HandleFunc("/health", health.Handler)
 	go func() {
 		err := http.ListenAndServe(":8080", nil)
@@ -224,6 +232,14 @@ respond:
 						Name:    response.Environment.Name,
 						RefName: response.Environment.RefName,
 					},
+					Findings: util.Map(response.Findings, func(f gitops.Finding) *pb.ValidationFinding {
+						return &pb.ValidationFinding{
+							Severity:  f.Severity.String(),
+							Message:   f.Message,
+							Path:      f.Path,
+							Validator: f.Validator,
+						}
+					}),
 				},
 			},
 		})
@@ -238,6 +254,64 @@ respond:
 	return nil
 }
 
+func (s *Server) PruneReviews(ctx context.Context, req *pb.PruneReviewsRequest) (*pb.PruneReviewsResponse, error) {
+	slog.Info("received prune request", "repository", req.GetConfigRepository().GetUrl(), "environment", req.Environment, "appName", req.AppName)
+
+	pruneRequest := &PruneRequest{
+		Repository: gitops.Repository{
+			URL: req.GetConfigRepository().GetUrl(),
+		},
+		Environment:              req.Environment,
+		AppName:                  req.AppName,
+		DeletedUpdateIdentifiers: req.DeletedUpdateIdentifiers,
+		DryRun:                   req.DryRun,
+	}
+	if pruneRequest.Repository.URL == "" {
+		return nil, status.Error(codes.InvalidArgument, "a configuration repository is required")
+	}
+
+	authorised, err := s.flow.Strategies.RequestAuthorisation.Authorise(&gitops.Request{
+		TargetRepository: pruneRequest.Repository,
+		Environment:      pruneRequest.Environment,
+		AppName:          pruneRequest.AppName,
+		DryRun:           pruneRequest.DryRun,
+	}, func(msg string) { slog.Debug(msg) })
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to authorise request: %s", err)
+	}
+	if !authorised {
+		return nil, status.Error(codes.PermissionDenied, "request is not authorised")
+	}
+
+	pruned, err := s.pruner().prune(ctx, pruneRequest)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to prune reviews: %s", err)
+	}
+
+	return &pb.PruneReviewsResponse{
+		DryRun: pruneRequest.DryRun,
+		Pruned: util.Map(pruned, func(p *PrunedBranch) *pb.PrunedBranch {
+			return &pb.PrunedBranch{
+				Branch:           p.Branch,
+				Environment:      p.Source.Environment,
+				AppName:          p.Source.AppName,
+				UpdateIdentifier: p.Source.UpdateIdentifier,
+				Reason:           p.Reason,
+				ReviewUrl:        p.ReviewURL,
+				BranchDeleted:    p.BranchDeleted,
+				Error:            p.Error,
+			}
+		}),
+	}, nil
+}
+
+func (s *Server) pruner() *pruner {
+	return &pruner{
+		flow:    s.flow,
+		options: &s.managerOpts.Pruning,
+	}
+}
+
 func assignRequestMetadataToRequest(metadata *pb.UpdateManifestMetadata, req *gitops.Request) {
 	req.TargetRepository = gitops.Repository{
 		URL: metadata.ConfigRepository.Url,
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"path"
+	"strings"
+)
+
+// Reports whether a slash-separated path matches a glob pattern. Patterns follow path.Match, with the addition of "**"
+// path elements which match zero or more directories. Patterns without a slash are matched against the base name only,
+// so "*.yaml" matches YAML files in any directory.
+func MatchGlob(pattern, name string) (bool, error) {
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(name))
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(patterns, names []string) (bool, error) {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				matched, err := matchSegments(patterns[1:], names[i:])
+				if matched || err != nil {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(names) == 0 {
+			return false, nil
+		}
+
+		matched, err := path.Match(patterns[0], names[0])
+		if !matched || err != nil {
+			return false, err
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+
+	return len(names) == 0, nil
+}
@@ -0,0 +1,72 @@
+package util
+
+import "strings"
+
+const defaultRegistry = "docker.io"
+
+type ImageReference struct {
+	Registry   string // e.g. "docker.io"
+	Repository string // e.g. "library/nginx"
+	Tag        string // Empty when the reference has no tag
+	Digest     string // e.g. "sha256:..." or empty when the reference has no digest
+}
+
+// Splits a container image reference into its name, tag and digest without applying any defaults
+func SplitImage(image string) (name, tag, digest string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		digest = image[i+1:]
+		image = image[:i]
+	}
+
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		tag = image[i+1:]
+		image = image[:i]
+	}
+
+	return image, tag, digest
+}
+
+// Joins an image name with an optional tag and digest
+func JoinImage(name, tag, digest string) string {
+	if tag != "" {
+		name += ":" + tag
+	}
+	if digest != "" {
+		name += "@" + digest
+	}
+
+	return name
+}
+
+// Parses a container image reference, applying the same defaults as Docker for the registry and official images
+func ParseImageReference(image string) ImageReference {
+	ref := ImageReference{}
+	image, ref.Tag, ref.Digest = SplitImage(image)
+
+	if i := strings.Index(image, "/"); i >= 0 {
+		first := image[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Registry = first
+			image = image[i+1:]
+		}
+	}
+
+	if ref.Registry == "" {
+		ref.Registry = defaultRegistry
+		if !strings.Contains(image, "/") {
+			image = "library/" + image
+		}
+	}
+	ref.Repository = image
+
+	return ref
+}
+
+// The fully qualified repository name, e.g. "docker.io/library/nginx"
+func (r ImageReference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+func (r ImageReference) String() string {
+	return JoinImage(r.Name(), r.Tag, r.Digest)
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Identifies a Kubernetes object in the form Kind/namespace/name. Cluster-scoped objects have an empty namespace.
+func ResourceID(obj *yaml.Node) string {
+	metadata := GetNode(obj, "metadata")
+
+	return fmt.Sprintf("%s/%s/%s", GetString(obj, "kind"), GetString(metadata, "namespace"), GetString(metadata, "name"))
+}
+
+// Returns the pod templates of a workload object. These are the mappings holding the metadata and spec of the pods.
+func PodTemplates(obj *yaml.Node) []*yaml.Node {
+	spec := GetNode(obj, "spec")
+	if spec == nil {
+		return nil
+	}
+
+	var template *yaml.Node
+	switch GetString(obj, "kind") {
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		template = GetNode(spec, "template")
+	case "CronJob":
+		template = GetNode(GetNode(GetNode(spec, "jobTemplate"), "spec"), "template")
+	}
+
+	if template == nil || template.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	return []*yaml.Node{template}
+}
+
+// Returns the pod specs of a workload object. Objects which are not workloads have none.
+func PodSpecs(obj *yaml.Node) []*yaml.Node {
+	if GetString(obj, "kind") == "Pod" {
+		spec := GetNode(obj, "spec")
+		if spec == nil || spec.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		return []*yaml.Node{spec}
+	}
+
+	podSpecs := make([]*yaml.Node, 0)
+	for _, template := range PodTemplates(obj) {
+		spec := GetNode(template, "spec")
+		if spec != nil && spec.Kind == yaml.MappingNode {
+			podSpecs = append(podSpecs, spec)
+		}
+	}
+
+	return podSpecs
+}
+
+// Whether a YAML mapping looks like a Kubernetes object
+func IsKubernetesObject(obj *yaml.Node) bool {
+	return obj != nil && obj.Kind == yaml.MappingNode && GetString(obj, "apiVersion") != "" && GetString(obj, "kind") != ""
+}
+
+// Returns the containers, init containers and ephemeral containers of a pod spec
+func Containers(podSpec *yaml.Node) []*yaml.Node {
+	containers := make([]*yaml.Node, 0)
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		list := GetNode(podSpec, field)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, container := range list.Content {
+			if container.Kind == yaml.MappingNode {
+				containers = append(containers, container)
+			}
+		}
+	}
+
+	return containers
+}
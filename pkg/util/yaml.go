@@ -1,6 +1,8 @@
 package util
 
 import (
+	"errors"
+	"io"
 	"sort"
 
 	"gopkg.in/yaml.v3"
@@ -24,7 +26,9 @@ func SetMappingValue(mapping *yaml.Node, key string, value string) {
 		v := mapping.Content[i+1]
 		if k.Value == key {
 			v.Kind = yaml.ScalarNode
+			v.Tag = "!!str"
 			v.Value = value
+			v.Content = nil
 			return
 		}
 	}
@@ -75,3 +79,60 @@ func GetOrCreateMap(parent *yaml.Node, key string) *yaml.Node {
 
 	return newMap
 }
+
+// Decodes every document of a multi-document YAML stream into document nodes
+func DecodeDocuments(r io.Reader) ([]*yaml.Node, error) {
+	documents := make([]*yaml.Node, 0)
+	decoder := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, &doc)
+	}
+
+	return documents, nil
+}
+
+// Returns the value node of a mapping key without modifying it, or nil if the key does not exist
+func GetNode(parent *yaml.Node, key string) *yaml.Node {
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			return parent.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// Returns the scalar value of a mapping key, or an empty string if the key does not exist or is not a scalar
+func GetString(parent *yaml.Node, key string) string {
+	node := GetNode(parent, key)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+
+	return node.Value
+}
+
+// Encodes document nodes as a multi-document YAML stream with an indent of two spaces
+func EncodeDocuments(w io.Writer, documents []*yaml.Node) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	for _, doc := range documents {
+		err := encoder.Encode(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.Close()
+}